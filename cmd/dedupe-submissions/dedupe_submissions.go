@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	_ "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/models/db"
+)
+
+// Removes the duplicate submissions of every user, keeping the first stored copy.
+// Submissions having a site id are compared by it, the older ones without an id
+// by their problem, time, status and language.
+func main() {
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+
+	withID := removeDuplicates(coll, bson.M{"id": bson.M{"$exists": true}}, bson.M{
+		"uid":  "$uid",
+		"site": "$site",
+		"id":   "$id",
+	})
+	withoutID := removeDuplicates(coll, bson.M{"id": bson.M{"$exists": false}}, bson.M{
+		"uid":        "$uid",
+		"site":       "$site",
+		"url":        "$url",
+		"created_at": "$created_at",
+		"status":     "$status",
+		"language":   "$language",
+	})
+	fmt.Printf("Removed %d duplicate submissions\n", withID+withoutID)
+}
+
+// Groups the submissions matching filter by key and removes all but the
+// first of every group. Returns the number of submissions removed.
+func removeDuplicates(coll *mgo.Collection, filter bson.M, key bson.M) int {
+	pipe := coll.Pipe([]bson.M{
+		{"$match": filter},
+		{"$sort": bson.M{"_id": 1}},
+		{"$group": bson.M{
+			"_id":   key,
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}).AllowDiskUse()
+	iter := pipe.Iter()
+	var group struct {
+		IDs []bson.ObjectId `bson:"ids"`
+	}
+	var removed int
+	for iter.Next(&group) {
+		info, err := coll.RemoveAll(bson.M{"_id": bson.M{"$in": group.IDs[1:]}})
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		removed += info.Removed
+	}
+	if err := iter.Close(); err != nil {
+		log.Println(err.Error())
+		os.Exit(1)
+	}
+	return removed
+}
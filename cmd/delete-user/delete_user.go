@@ -2,49 +2,32 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/globalsign/mgo/bson"
 	_ "github.com/mdg-iitr/Codephile/conf"
-
-	"os"
-
 	"github.com/mdg-iitr/Codephile/models"
-	"github.com/mdg-iitr/Codephile/models/db"
-	"github.com/mdg-iitr/Codephile/services/firebase"
-
-	"github.com/mdg-iitr/Codephile/services/auth"
 )
 
+// Deletes the user along with all the data of the user, recording it in the audit log along with the optional reason
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run ./delete_user <uid>")
+		fmt.Println("Usage: go run ./delete_user <uid> [reason]")
 		os.Exit(1)
 	}
-	user, err := models.GetUser(bson.ObjectIdHex(os.Args[1]))
-	if err != nil {
-		panic(err)
+	if !bson.IsObjectIdHex(os.Args[1]) {
+		fmt.Println("Invalid uid")
+		os.Exit(1)
 	}
-	if user.Picture != "" {
-		// Delete profile pic
-		pic_name := strings.Split(user.Picture, "/profile/")[1]
-		err = firebase.DeleteObject("profile/" + pic_name)
-		if err != nil {
-			panic(err)
-		}
+	details := map[string]string{"via": "cli"}
+	if len(os.Args) > 2 {
+		details["reason"] = strings.Join(os.Args[2:], " ")
 	}
-	// Delete user from our database
-	sess := db.NewUserCollectionSession()
-	defer sess.Close()
-	coll := sess.Collection
-	err = coll.RemoveId(user.ID)
+	err := models.DeleteUser("", bson.ObjectIdHex(os.Args[1]), details)
 	if err != nil {
-		panic(err)
-	}
-	// Lastly block all the issued tokens
-	err = auth.BlacklistUser(user.ID)
-	if err != nil {
-		panic(err)
+		fmt.Println(err.Error())
+		os.Exit(1)
 	}
 	fmt.Println("Success")
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/globalsign/mgo/bson"
+	_ "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	_ "github.com/mdg-iitr/Codephile/scrappers/all"
+)
+
+// Moves the submissions embedded in the user documents to the submissions collection.
+// Safe to run again if interrupted, the submissions of a user are removed
+// from the user document only after being copied.
+func main() {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	subSess := db.NewSubmissionCollectionSession()
+	defer subSess.Close()
+	subColl := subSess.Collection
+
+	iter := coll.Find(bson.M{"submissions": bson.M{"$exists": true}}).Select(bson.M{"_id": 1}).Iter()
+	var user types.User
+	var users, moved int
+	for iter.Next(&user) {
+		var u types.User
+		err := coll.FindId(user.ID).Select(bson.M{"submissions": 1}).One(&u)
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		if len(u.Submissions) != 0 {
+			// Drop the copies left behind by an interrupted run. Embedded
+			// submissions are sorted, newest first.
+			_, err = subColl.RemoveAll(bson.M{
+				"uid":        user.ID,
+				"created_at": bson.M{"$lte": u.Submissions[0].CreationDate},
+			})
+			if err != nil {
+				log.Println(err.Error())
+				continue
+			}
+			bulk := subColl.Bulk()
+			bulk.Unordered()
+			for _, sub := range u.Submissions {
+				sub.UserID = user.ID
+				sub.Site, err = scrappers.SiteFromURL(sub.URL)
+				if err != nil {
+					log.Println(err.Error())
+					sub.Site = ""
+				}
+				bulk.Insert(sub)
+			}
+			_, err = bulk.Run()
+			if err != nil {
+				log.Println(err.Error())
+				continue
+			}
+		}
+		err = coll.UpdateId(user.ID, bson.M{"$unset": bson.M{"submissions": ""}})
+		if err != nil {
+			log.Println(err.Error())
+			continue
+		}
+		users++
+		moved += len(u.Submissions)
+	}
+	if err := iter.Close(); err != nil {
+		log.Println(err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("Moved %d submissions of %d users\n", moved, users)
+}
@@ -2,20 +2,32 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strings"
+
 	"github.com/globalsign/mgo/bson"
 	_ "github.com/mdg-iitr/Codephile/conf"
-	"github.com/mdg-iitr/Codephile/services/auth"
-	"os"
+	"github.com/mdg-iitr/Codephile/models"
 )
 
+// Allows the blacklisted user to log in again, recording it in the audit log along with the optional reason
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run ./blacklist_user <uid>")
+		fmt.Println("Usage: go run ./whitelist_user <uid> [reason]")
+		os.Exit(1)
+	}
+	if !bson.IsObjectIdHex(os.Args[1]) {
+		fmt.Println("Invalid uid")
 		os.Exit(1)
 	}
-	err := auth.WhitelistUser(bson.ObjectId(os.Args[1]))
+	details := map[string]string{"via": "cli"}
+	if len(os.Args) > 2 {
+		details["reason"] = strings.Join(os.Args[2:], " ")
+	}
+	err := models.WhitelistUser("", bson.ObjectIdHex(os.Args[1]), details)
 	if err != nil {
 		fmt.Println(err.Error())
+		os.Exit(1)
 	}
-
+	fmt.Println("Success")
 }
@@ -0,0 +1,391 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/astaxie/beego"
+	"github.com/getsentry/sentry-go"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/services/auth"
+	"github.com/mdg-iitr/Codephile/services/scheduler"
+	"github.com/mdg-iitr/Codephile/services/worker"
+)
+
+// Controller for operations restricted to the admins
+type AdminController struct {
+	beego.Controller
+}
+
+// Prepare allows only the admins, with a token granted the admin scope
+func (a *AdminController) Prepare() {
+	uid, _ := a.Ctx.Input.GetData("uid").(bson.ObjectId)
+	if !isAdmin(uid) {
+		a.Ctx.ResponseWriter.WriteHeader(http.StatusForbidden)
+		a.Data["json"] = ForbiddenError("Admin access required")
+		a.ServeJSON()
+		a.StopRun()
+	}
+	if !checkScope(&a.Controller) {
+		a.StopRun()
+	}
+}
+
+func (a *AdminController) serverError(err error) {
+	hub := sentry.GetHubFromContext(a.Ctx.Request.Context())
+	hub.CaptureException(err)
+	log.Println(err.Error())
+	a.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+	a.Data["json"] = InternalServerError("Internal server error")
+	a.ServeJSON()
+}
+
+// Records the action in the audit log before it is taken, so that no
+// action goes unrecorded. Writes the error response and returns false if
+// the action can't be recorded.
+func (a *AdminController) audit(action string, target bson.ObjectId, details map[string]string) bool {
+	admin := a.Ctx.Input.GetData("uid").(bson.ObjectId)
+	if err := models.AddAuditEntry(admin, action, target, details); err != nil {
+		a.serverError(err)
+		return false
+	}
+	return true
+}
+
+// Details of the action recorded in the audit log, holding the reason
+// given by the admin, if any
+func (a *AdminController) auditDetails() map[string]string {
+	details := map[string]string{}
+	if reason := a.GetString("reason"); reason != "" {
+		details["reason"] = reason
+	}
+	return details
+}
+
+// Returns the uid of the user the request is about. Writes the error
+// response and returns false if no such user exists.
+func (a *AdminController) targetUser() (bson.ObjectId, bool) {
+	uid := a.GetString(":uid")
+	if !bson.IsObjectIdHex(uid) {
+		a.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		a.Data["json"] = BadInputError("Invalid uid")
+		a.ServeJSON()
+		return "", false
+	}
+	exists, err := models.UidExists(bson.ObjectIdHex(uid))
+	if err != nil {
+		a.serverError(err)
+		return "", false
+	} else if !exists {
+		a.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		a.Data["json"] = NotFoundError("User not found")
+		a.ServeJSON()
+		return "", false
+	}
+	return bson.ObjectIdHex(uid), true
+}
+
+// @Title Scheduler Status
+// @Description Returns the state of the refresh scheduler along with the worker queue
+// @Security token_auth admin
+// @Success 200 {object} scheduler.Status
+// @Failure 403 not an admin
+// @Failure 500 server_error
+// @router /scheduler [get]
+func (a *AdminController) SchedulerStatus() {
+	status, err := scheduler.GetStatus()
+	if err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = status
+	a.ServeJSON()
+}
+
+// @Title Pause Scheduler
+// @Description Stops scheduling of refresh jobs, jobs already in queue are still performed
+// @Security token_auth admin
+// @Success 200 {string} scheduler paused
+// @Failure 403 not an admin
+// @Failure 500 server_error
+// @router /scheduler/pause [post]
+func (a *AdminController) PauseScheduler() {
+	if !a.audit(types.AuditPauseScheduler, "", nil) {
+		return
+	}
+	if err := scheduler.Pause(); err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = map[string]string{"status": "scheduler paused"}
+	a.ServeJSON()
+}
+
+// @Title Resume Scheduler
+// @Description Resumes scheduling of refresh jobs
+// @Security token_auth admin
+// @Success 200 {string} scheduler resumed
+// @Failure 403 not an admin
+// @Failure 500 server_error
+// @router /scheduler/resume [post]
+func (a *AdminController) ResumeScheduler() {
+	if !a.audit(types.AuditResumeScheduler, "", nil) {
+		return
+	}
+	if err := scheduler.Resume(); err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = map[string]string{"status": "scheduler resumed"}
+	a.ServeJSON()
+}
+
+// @Title Queue
+// @Description Returns the jobs in the worker queue, up to limit jobs in each state
+// @Security token_auth admin
+// @Param	limit		query 	int	false		"jobs shown in each state, 50 by default"
+// @Success 200 {object} worker.QueueView
+// @Failure 400 invalid limit
+// @Failure 403 not an admin
+// @Failure 500 server_error
+// @router /queue [get]
+func (a *AdminController) Queue() {
+	limit, err := a.GetInt64("limit", 50)
+	if err != nil || limit <= 0 || limit > maxPageSize {
+		a.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		a.Data["json"] = BadInputError("Invalid limit")
+		a.ServeJSON()
+		return
+	}
+	if !a.audit(types.AuditViewQueue, "", nil) {
+		return
+	}
+	view, err := worker.Inspect(limit)
+	if err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = view
+	a.ServeJSON()
+}
+
+// @Title Lookup User
+// @Description Returns the account details of the user with the given uid, username or email
+// @Security token_auth admin
+// @Param	key		path 	string	true		"uid, username or email of the user"
+// @Success 200 {object} types.AdminUser
+// @Failure 403 not an admin
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /users/:key [get]
+func (a *AdminController) LookupUser() {
+	user, err := models.LookupUser(a.GetString(":key"))
+	if err == UserNotFoundError {
+		a.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		a.Data["json"] = NotFoundError("User not found")
+		a.ServeJSON()
+		return
+	} else if err != nil {
+		a.serverError(err)
+		return
+	}
+	if !a.audit(types.AuditLookupUser, user.ID, map[string]string{"key": a.GetString(":key")}) {
+		return
+	}
+	user.Blacklisted = auth.IsUserBlacklisted(user.ID)
+	a.Data["json"] = user
+	a.ServeJSON()
+}
+
+// @Title Blacklist User
+// @Description Logs the user out everywhere and blocks the user from logging in again
+// @Security token_auth admin
+// @Param	uid		path 	string	true		"uid of the user"
+// @Param	reason		formData 	string	false		"reason recorded in the audit log"
+// @Success 200 {string} user blacklisted
+// @Failure 400 invalid uid
+// @Failure 403 not an admin
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /users/:uid/blacklist [post]
+func (a *AdminController) BlacklistUser() {
+	uid, ok := a.targetUser()
+	if !ok {
+		return
+	}
+	admin := a.Ctx.Input.GetData("uid").(bson.ObjectId)
+	if err := models.BlacklistUser(admin, uid, a.auditDetails()); err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = map[string]string{"status": "user blacklisted"}
+	a.ServeJSON()
+}
+
+// @Title Whitelist User
+// @Description Allows the blacklisted user to log in again
+// @Security token_auth admin
+// @Param	uid		path 	string	true		"uid of the user"
+// @Param	reason		formData 	string	false		"reason recorded in the audit log"
+// @Success 200 {string} user whitelisted
+// @Failure 400 invalid uid
+// @Failure 403 not an admin
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /users/:uid/whitelist [post]
+func (a *AdminController) WhitelistUser() {
+	uid, ok := a.targetUser()
+	if !ok {
+		return
+	}
+	admin := a.Ctx.Input.GetData("uid").(bson.ObjectId)
+	if err := models.WhitelistUser(admin, uid, a.auditDetails()); err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = map[string]string{"status": "user whitelisted"}
+	a.ServeJSON()
+}
+
+// @Title Delete User
+// @Description Deletes the user along with the profile picture, submissions, participations and API keys, and blocks the issued tokens
+// @Security token_auth admin
+// @Param	uid		path 	string	true		"uid of the user"
+// @Param	reason		query 	string	false		"reason recorded in the audit log"
+// @Success 200 {string} user deleted
+// @Failure 400 invalid uid
+// @Failure 403 not an admin
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /users/:uid [delete]
+func (a *AdminController) DeleteUser() {
+	uid, ok := a.targetUser()
+	if !ok {
+		return
+	}
+	admin := a.Ctx.Input.GetData("uid").(bson.ObjectId)
+	if err := models.DeleteUser(admin, uid, a.auditDetails()); err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = map[string]string{"status": "user deleted"}
+	a.ServeJSON()
+}
+
+// @Title Refresh User
+// @Description Queues the refresh of the submissions and profiles of the user on every site the user has a handle on
+// @Security token_auth admin
+// @Param	uid		path 	string	true		"uid of the user"
+// @Success 201 {object} map[string]string status of the refresh of each site
+// @Failure 400 invalid uid
+// @Failure 403 not an admin
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /users/:uid/refresh [post]
+func (a *AdminController) RefreshUser() {
+	uid, ok := a.targetUser()
+	if !ok || !a.audit(types.AuditRefreshUser, uid, nil) {
+		return
+	}
+	statuses, err := models.GetFetchStatus(uid)
+	if err != nil {
+		a.serverError(err)
+		return
+	}
+	queued := map[string]string{}
+	for site := range statuses {
+		// Fails if the job is already queued or the queue is full
+		if err := worker.Enqueue(worker.NewJob(uid, site, models.RefreshSite)); err != nil {
+			queued[site] = err.Error()
+		} else {
+			queued[site] = "queued"
+		}
+	}
+	a.Ctx.ResponseWriter.WriteHeader(http.StatusCreated)
+	a.Data["json"] = queued
+	a.ServeJSON()
+}
+
+// @Title Grant Admin
+// @Description Gives the admin role to the user, who needs to log in again to use the admin endpoints
+// @Security token_auth admin
+// @Param	uid		path 	string	true		"uid of the user"
+// @Success 200 {string} admin role granted
+// @Failure 400 invalid uid
+// @Failure 403 not an admin
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /users/:uid/admin [post]
+func (a *AdminController) GrantAdmin() {
+	uid, ok := a.targetUser()
+	if !ok || !a.audit(types.AuditGrantAdmin, uid, nil) {
+		return
+	}
+	if err := models.SetRole(uid, types.RoleAdmin); err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = map[string]string{"status": "admin role granted"}
+	a.ServeJSON()
+}
+
+// @Title Revoke Admin
+// @Description Takes the admin role away from the user. Users listed in ADMIN_UIDS stay admins
+// @Security token_auth admin
+// @Param	uid		path 	string	true		"uid of the user"
+// @Success 200 {string} admin role revoked
+// @Failure 400 invalid uid
+// @Failure 403 not an admin
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /users/:uid/admin [delete]
+func (a *AdminController) RevokeAdmin() {
+	uid, ok := a.targetUser()
+	if !ok || !a.audit(types.AuditRevokeAdmin, uid, nil) {
+		return
+	}
+	if err := models.SetRole(uid, ""); err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = map[string]string{"status": "admin role revoked"}
+	a.ServeJSON()
+}
+
+// @Title Audit Log
+// @Description Returns the actions taken by the admins, latest first
+// @Security token_auth admin
+// @Param	uid		query 	string	false		"uid of the user to limit the log to the actions on"
+// @Param	cursor		query 	string	false		"next_cursor of the previous page"
+// @Param	count		query 	int	false		"entries in the page, 100 by default"
+// @Success 200 {object} types.AuditPage
+// @Failure 400 invalid uid, cursor or count
+// @Failure 403 not an admin
+// @Failure 500 server_error
+// @router /audit [get]
+func (a *AdminController) AuditLog() {
+	var target bson.ObjectId
+	if uid := a.GetString("uid"); uid != "" {
+		if !bson.IsObjectIdHex(uid) {
+			a.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+			a.Data["json"] = BadInputError("Invalid uid")
+			a.ServeJSON()
+			return
+		}
+		target = bson.ObjectIdHex(uid)
+	}
+	cursor, count, ok := parsePage(&a.Controller)
+	if !ok {
+		return
+	}
+	page, err := models.GetAuditLog(target, cursor, count)
+	if err != nil {
+		a.serverError(err)
+		return
+	}
+	a.Data["json"] = page
+	a.ServeJSON()
+}
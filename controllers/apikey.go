@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/types"
+)
+
+// @Title Create API Key
+// @Description Creates an API key with the given scopes for the logged in user. The key is returned only once
+// @Security token_auth write:user
+// @Param	name		formData 	string	true		"Name to recognise the key by"
+// @Param	scopes		formData 	string	true		"Comma separated scopes of the key e.g. read:user,read:submission"
+// @Success 201 {object} types.NewAPIKey
+// @Failure 400 invalid name or scopes
+// @Failure 401 invalid authentication token
+// @Failure 403 request made with an API key
+// @Failure 409 too many API keys
+// @Failure 500 server_error
+// @router /apikeys [post]
+func (u *UserController) CreateAPIKey() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	name := strings.TrimSpace(u.GetString("name"))
+	if name == "" {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		u.Data["json"] = BadInputError("Invalid name")
+		u.ServeJSON()
+		return
+	}
+	var scopes []string
+	for _, scope := range strings.Split(u.GetString("scopes"), ",") {
+		scope = strings.TrimSpace(scope)
+		if !types.IsScopeValid(scope) {
+			u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+			u.Data["json"] = BadInputError("Invalid scope " + scope)
+			u.ServeJSON()
+			return
+		}
+		scopes = append(scopes, scope)
+	}
+	key, err := models.CreateAPIKey(uid, name, scopes)
+	if err == APIKeyLimitError {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusConflict)
+		u.Data["json"] = AlreadyExistsError("Too many API keys")
+		u.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Ctx.ResponseWriter.WriteHeader(http.StatusCreated)
+	u.Data["json"] = key
+	u.ServeJSON()
+}
+
+// @Title API Keys
+// @Description Lists the API keys of the logged in user, latest first
+// @Security token_auth read:user
+// @Success 200 {object} []types.APIKey
+// @Failure 401 invalid authentication token
+// @Failure 403 request made with an API key
+// @Failure 500 server_error
+// @router /apikeys [get]
+func (u *UserController) GetAPIKeys() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	keys, err := models.GetAPIKeys(uid)
+	if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = keys
+	u.ServeJSON()
+}
+
+// @Title Delete API Key
+// @Description Revokes the API key with the given id
+// @Security token_auth write:user
+// @Param	id		path 	string	true		"id of the API key"
+// @Success 200 {string} API key deleted
+// @Failure 400 invalid id
+// @Failure 401 invalid authentication token
+// @Failure 403 request made with an API key
+// @Failure 404 API key not found
+// @Failure 500 server_error
+// @router /apikeys/:id [delete]
+func (u *UserController) DeleteAPIKey() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	id := u.GetString(":id")
+	if !bson.IsObjectIdHex(id) {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		u.Data["json"] = BadInputError("Invalid id")
+		u.ServeJSON()
+		return
+	}
+	err := models.DeleteAPIKey(uid, bson.ObjectIdHex(id))
+	if err == APIKeyNotFoundError {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		u.Data["json"] = NotFoundError("API key not found")
+		u.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = map[string]string{"status": "API key deleted"}
+	u.ServeJSON()
+}
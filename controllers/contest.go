@@ -4,13 +4,13 @@ import (
 	// "encoding/json"
 	"github.com/astaxie/beego"
 	"github.com/getsentry/sentry-go"
-	. "github.com/mdg-iitr/Codephile/conf"
 	"github.com/mdg-iitr/Codephile/errors"
 	"log"
 	"net/http"
 
-	//"github.com/globalsign/mgo/bson"
+	"github.com/globalsign/mgo/bson"
 	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/scrappers"
 )
 
 //Controller to display contests
@@ -18,6 +18,13 @@ type ContestController struct {
 	beego.Controller
 }
 
+// Prepare rejects tokens lacking the scope of the endpoint
+func (u *ContestController) Prepare() {
+	if !checkScope(&u.Controller) {
+		u.StopRun()
+	}
+}
+
 // @Title GetContests
 // @Description displays all contests
 // @Security token_auth read:contests
@@ -50,7 +57,7 @@ func (u *ContestController) GetContests() {
 // @router /:site [get]
 func (u *ContestController) GetSpecificContests() {
 	site := u.GetString(":site")
-	if !IsSiteValid(site) {
+	if !scrappers.IsSiteValid(site) {
 		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
 		u.Data["json"] = errors.BadInputError("Invalid contest site")
 		u.ServeJSON()
@@ -70,3 +77,81 @@ func (u *ContestController) GetSpecificContests() {
 	u.Data["json"] = contests
 	u.ServeJSON()
 }
+
+// @Title Get Participations
+// @Description Returns the contests taken part in by the user with given uid (Logged-in user if uid is empty), latest first
+// @Security token_auth read:contests
+// @Param	uid		path 	string	false		"uid of user"
+// @Success 200 {object} []types.ContestParticipation
+// @Failure 400 invalid uid
+// @Failure 403 profile is private
+// @Failure 500 server_error
+// @router /participation [get]
+// @router /participation/:uid [get]
+func (u *ContestController) GetParticipations() {
+	uidString := u.GetString(":uid")
+	var uid bson.ObjectId
+	if bson.IsObjectIdHex(uidString) {
+		uid = bson.ObjectIdHex(uidString)
+	} else if uidString == "" {
+		uid = u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	} else {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		u.Data["json"] = errors.BadInputError("Invalid UID")
+		u.ServeJSON()
+		return
+	}
+	if !checkCanView(&u.Controller, uid) {
+		return
+	}
+	contests, err := models.GetParticipations(uid)
+	if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = errors.InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = contests
+	u.ServeJSON()
+}
+
+// @Title Get Following Participations
+// @Description Returns how the logged-in user and the users followed by them performed in a contest, ordered by rank
+// @Security token_auth read:contests
+// @Param	site		path 	string	true		"site name"
+// @Param	contest_id	path 	string	true		"id of the contest on the site"
+// @Success 200 {object} []types.FollowingParticipation
+// @Failure 400 incorrect site
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /participation/:site/:contest_id/following [get]
+func (u *ContestController) GetFollowingParticipations() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	site := u.GetString(":site")
+	if !scrappers.IsSiteValid(site) {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		u.Data["json"] = errors.BadInputError("Invalid contest site")
+		u.ServeJSON()
+		return
+	}
+	contests, err := models.GetFollowingParticipations(uid, site, u.GetString(":contest_id"))
+	if err == errors.UserNotFoundError {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		u.Data["json"] = errors.NotFoundError("User not found")
+		u.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = errors.InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = contests
+	u.ServeJSON()
+}
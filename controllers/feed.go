@@ -8,13 +8,19 @@ import (
 	"github.com/mdg-iitr/Codephile/models"
 	"log"
 	"net/http"
-	"time"
 )
 
 type FeedController struct {
 	beego.Controller
 }
 
+// Prepare rejects tokens lacking the scope of the endpoint
+func (f *FeedController) Prepare() {
+	if !checkScope(&f.Controller) {
+		f.StopRun()
+	}
+}
+
 // @Title ContestsFeed
 // @Description Provides Data for contests in the Feed
 // @Security token_auth read:feed
@@ -60,26 +66,22 @@ func (f *FeedController) AllFeed() {
 }
 
 // @Title FriendFeed
-// @Description Gives submission feed in paginated manner giving 100 submissions at a time
+// @Description Gives submission feed in paginated manner, 100 submissions at a time by default, along with the cursor of the next page
 // @Security token_auth read:feed
-// @Param	before		query 	string	true  "Time before which feed to be returned, uses current time if empty or not present"
-// @Success 200 {object} []types.FeedObject
-// @Failure 400 invalid before value
+// @Param	cursor		query 	string	false  "Cursor of the page, next_cursor of the previous page. Starts from before if empty"
+// @Param	before		query 	string	false  "Time before which feed to be returned, uses current time if empty or not present"
+// @Param	count		query 	int	false  "No of submissions in the page, at most 200"
+// @Success 200 {object} types.FeedPage
+// @Failure 400 invalid cursor, before or count value
 // @Failure 500 server_error
 // @router /friend-activity [get]
 func (f *FeedController) PaginatedFeed() {
 	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
-	before, err := f.GetInt64("before", time.Now().UTC().Unix())
-	if err != nil {
-		f.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
-		f.Data["json"] = errors.BadInputError("Invalid query param value")
-		f.ServeJSON()
+	cursor, count, ok := parsePage(&f.Controller)
+	if !ok {
 		return
 	}
-	if before == 0 {
-		before = time.Now().UTC().Unix()
-	}
-	feed, err := models.GetFeed(uid, time.Unix(before, 0))
+	feed, err := models.GetFeed(uid, cursor, count)
 	if err != nil {
 		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
 		hub.CaptureException(err)
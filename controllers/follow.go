@@ -16,12 +16,21 @@ type FriendsController struct {
 	beego.Controller
 }
 
+// Prepare rejects tokens lacking the scope of the endpoint
+func (f *FriendsController) Prepare() {
+	if !checkScope(&f.Controller) {
+		f.StopRun()
+	}
+}
+
 // @Title FollowUser
-// @Description Adds the Following user's uid to the database
+// @Description Adds the Following user's uid to the database. Sends a follow request instead if the profile of the user is not public
 // @Security token_auth write:follow
 // @Param	uid2		query 	string	true  "uid of user to follow"
 // @Success 200  {string} user followed
+// @Success 202  {string} follow requested
 // @Failure 400 bad uid
+// @Failure 403 user blocked
 // @Failure 500 server_error
 // @router /follow [post]
 func (f *FriendsController) FollowUser() {
@@ -33,8 +42,13 @@ func (f *FriendsController) FollowUser() {
 		f.ServeJSON()
 		return
 	}
-	err := models.FollowUser(uid1, bson.ObjectIdHex(uid2))
-	if err != nil {
+	requested, err := models.FollowUser(uid1, bson.ObjectIdHex(uid2))
+	if err == errors.UserBlockedError {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusForbidden)
+		f.Data["json"] = errors.ForbiddenError("User blocked")
+		f.ServeJSON()
+		return
+	} else if err != nil {
 		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
 		hub.CaptureException(err)
 		log.Println(err.Error())
@@ -43,6 +57,12 @@ func (f *FriendsController) FollowUser() {
 		f.ServeJSON()
 		return
 	}
+	if requested {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusAccepted)
+		f.Data["json"] = map[string]string{"status": "Follow requested"}
+		f.ServeJSON()
+		return
+	}
 	//user2 has been followed
 	f.Data["json"] = map[string]string{"status": "User Followed"}
 	f.ServeJSON()
@@ -90,6 +110,7 @@ func (f *FriendsController) UnFollowUser() {
 // @Param	uid2		query 	string	true  "uid of following"
 // @Success 200 {object} types.AllWorldRanks
 // @Failure 400 bad uid
+// @Failure 403 profile is private or either user blocked the other
 // @Failure 500 server_error
 // @router /compare [get]
 func (f *FriendsController) CompareUser() {
@@ -101,6 +122,9 @@ func (f *FriendsController) CompareUser() {
 		f.ServeJSON()
 		return
 	}
+	if !checkCanView(&f.Controller, bson.ObjectIdHex(uid2)) {
+		return
+	}
 	worldRanks, err := models.CompareUser(uid1, bson.ObjectIdHex(uid2))
 	if err != nil {
 		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
@@ -136,3 +160,332 @@ func (f *FriendsController) GetFollowing() {
 	f.Data["json"] = following
 	f.ServeJSON()
 }
+
+// @Title GetFollowers
+// @Description Fetches the users following the user
+// @Security token_auth read:follow
+// @Success 200 {object} []types.FollowingUser
+// @Failure 500 server_error
+// @router /followers [get]
+func (f *FriendsController) GetFollowers() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	followers, err := models.GetFollowers(uid)
+	if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = followers
+	f.ServeJSON()
+}
+
+// @Title GetRecommendations
+// @Description Suggests users to follow from the same institute, followed by the users being followed or solving the same problems, highest score first
+// @Security token_auth read:follow
+// @Param	count		query 	int	false		"No of suggestions to be returned, 20 by default"
+// @Success 200 {object} []types.Recommendation
+// @Failure 400 bad count
+// @Failure 500 server_error
+// @router /recommendations [get]
+func (f *FriendsController) GetRecommendations() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	count, err := f.GetInt("count", 20)
+	if err != nil || count <= 0 || count > 100 {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		f.Data["json"] = errors.BadInputError("Invalid count")
+		f.ServeJSON()
+		return
+	}
+	recommendations, err := models.GetRecommendations(uid, count)
+	if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = recommendations
+	f.ServeJSON()
+}
+
+// @Title GetFollowRequests
+// @Description Fetches the users waiting for approval to follow the user, earliest first
+// @Security token_auth read:follow
+// @Success 200 {object} []types.FollowingUser
+// @Failure 500 server_error
+// @router /requests [get]
+func (f *FriendsController) GetFollowRequests() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	requests, err := models.GetFollowRequests(uid)
+	if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = requests
+	f.ServeJSON()
+}
+
+// @Title AcceptFollowRequest
+// @Description Approves the follow request of the user with the given uid
+// @Security token_auth write:follow
+// @Param	uid2		query 	string	true  "uid of user who requested to follow"
+// @Success 200  {string} follow request accepted
+// @Failure 400 bad uid
+// @Failure 404 follow request not found
+// @Failure 500 server_error
+// @router /requests/accept [post]
+func (f *FriendsController) AcceptFollowRequest() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	uid2 := f.GetString("uid2")
+	if uid2 == "" || !bson.IsObjectIdHex(uid2) {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		f.Data["json"] = errors.BadInputError("Invalid UID")
+		f.ServeJSON()
+		return
+	}
+	err := models.AcceptFollowRequest(uid, bson.ObjectIdHex(uid2))
+	if err == errors.FollowRequestNotFoundError {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		f.Data["json"] = errors.NotFoundError("Follow request not found")
+		f.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = map[string]string{"status": "Follow request accepted"}
+	f.ServeJSON()
+}
+
+// @Title RejectFollowRequest
+// @Description Declines the follow request of the user with the given uid
+// @Security token_auth write:follow
+// @Param	uid2		query 	string	true  "uid of user who requested to follow"
+// @Success 200  {string} follow request rejected
+// @Failure 400 bad uid
+// @Failure 404 follow request not found
+// @Failure 500 server_error
+// @router /requests/reject [post]
+func (f *FriendsController) RejectFollowRequest() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	uid2 := f.GetString("uid2")
+	if uid2 == "" || !bson.IsObjectIdHex(uid2) {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		f.Data["json"] = errors.BadInputError("Invalid UID")
+		f.ServeJSON()
+		return
+	}
+	err := models.RejectFollowRequest(uid, bson.ObjectIdHex(uid2))
+	if err == errors.FollowRequestNotFoundError {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		f.Data["json"] = errors.NotFoundError("Follow request not found")
+		f.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = map[string]string{"status": "Follow request rejected"}
+	f.ServeJSON()
+}
+
+// @Title Block User
+// @Description Blocks the user with the given uid. Removes the follows between the users in both directions and hides them from each other in search and rank results
+// @Security token_auth write:follow
+// @Param	uid2		query 	string	true  "uid of user to block"
+// @Success 200  {string} user blocked
+// @Failure 400 bad uid
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /block [post]
+func (f *FriendsController) BlockUser() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	uid2 := f.GetString("uid2")
+	if uid2 == "" || !bson.IsObjectIdHex(uid2) {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		f.Data["json"] = errors.BadInputError("Invalid UID")
+		f.ServeJSON()
+		return
+	}
+	err := models.BlockUser(uid, bson.ObjectIdHex(uid2))
+	if err == errors.UserNotFoundError {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		f.Data["json"] = errors.NotFoundError("User not found")
+		f.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = map[string]string{"status": "User Blocked"}
+	f.ServeJSON()
+}
+
+// @Title Unblock User
+// @Description Unblocks the user with the given uid
+// @Security token_auth write:follow
+// @Param	uid2		query 	string	true  "uid of user to unblock"
+// @Success 200  {string} user unblocked
+// @Failure 400 bad uid
+// @Failure 500 server_error
+// @router /unblock [post]
+func (f *FriendsController) UnblockUser() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	uid2 := f.GetString("uid2")
+	if uid2 == "" || !bson.IsObjectIdHex(uid2) {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		f.Data["json"] = errors.BadInputError("Invalid UID")
+		f.ServeJSON()
+		return
+	}
+	err := models.UnblockUser(uid, bson.ObjectIdHex(uid2))
+	if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = map[string]string{"status": "User Unblocked"}
+	f.ServeJSON()
+}
+
+// @Title Mute User
+// @Description Hides the submissions of the user with the given uid from the feed
+// @Security token_auth write:follow
+// @Param	uid2		query 	string	true  "uid of user to mute"
+// @Success 200  {string} user muted
+// @Failure 400 bad uid
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /mute [post]
+func (f *FriendsController) MuteUser() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	uid2 := f.GetString("uid2")
+	if uid2 == "" || !bson.IsObjectIdHex(uid2) {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		f.Data["json"] = errors.BadInputError("Invalid UID")
+		f.ServeJSON()
+		return
+	}
+	err := models.MuteUser(uid, bson.ObjectIdHex(uid2))
+	if err == errors.UserNotFoundError {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		f.Data["json"] = errors.NotFoundError("User not found")
+		f.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = map[string]string{"status": "User Muted"}
+	f.ServeJSON()
+}
+
+// @Title Unmute User
+// @Description Shows the submissions of the user with the given uid in the feed again
+// @Security token_auth write:follow
+// @Param	uid2		query 	string	true  "uid of user to unmute"
+// @Success 200  {string} user unmuted
+// @Failure 400 bad uid
+// @Failure 500 server_error
+// @router /unmute [post]
+func (f *FriendsController) UnmuteUser() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	uid2 := f.GetString("uid2")
+	if uid2 == "" || !bson.IsObjectIdHex(uid2) {
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		f.Data["json"] = errors.BadInputError("Invalid UID")
+		f.ServeJSON()
+		return
+	}
+	err := models.UnmuteUser(uid, bson.ObjectIdHex(uid2))
+	if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = map[string]string{"status": "User Unmuted"}
+	f.ServeJSON()
+}
+
+// @Title GetBlockedUsers
+// @Description Fetches the users blocked by the user
+// @Security token_auth read:follow
+// @Success 200 {object} []types.FollowingUser
+// @Failure 500 server_error
+// @router /blocked [get]
+func (f *FriendsController) GetBlockedUsers() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	users, err := models.GetBlockedUsers(uid)
+	if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = users
+	f.ServeJSON()
+}
+
+// @Title GetMutedUsers
+// @Description Fetches the users muted by the user
+// @Security token_auth read:follow
+// @Success 200 {object} []types.FollowingUser
+// @Failure 500 server_error
+// @router /muted [get]
+func (f *FriendsController) GetMutedUsers() {
+	uid := f.Ctx.Input.GetData("uid").(bson.ObjectId)
+	users, err := models.GetMutedUsers(uid)
+	if err != nil {
+		hub := sentry.GetHubFromContext(f.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		f.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		f.Data["json"] = errors.InternalServerError("Internal server error")
+		f.ServeJSON()
+		return
+	}
+	f.Data["json"] = users
+	f.ServeJSON()
+}
@@ -5,15 +5,25 @@ import (
 
 	"github.com/astaxie/beego"
 	"github.com/getsentry/sentry-go"
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
 )
 
 type GraphController struct {
 	beego.Controller
 }
 
+// Prepare rejects tokens lacking the scope of the endpoint
+func (g *GraphController) Prepare() {
+	if !checkScope(&g.Controller) {
+		g.StopRun()
+	}
+}
+
 // @Title Activity Graph
 // @Description Gives the activity graph for a user with given uid, (Logged-in user if uid is empty)
 // @Security token_auth read:user
@@ -21,6 +31,7 @@ type GraphController struct {
 // @Success 200 {object} types.ActivityGraph
 // @Failure 401 : Unauthorized
 // @Failure 400 :uid is invalid
+// @Failure 403 profile is private
 // @Failure 404 user not found
 // @Failure 500 server_error
 // @router /activity [get]
@@ -38,6 +49,9 @@ func (g *GraphController) GetActivityGraph() {
 		g.ServeJSON()
 		return
 	}
+	if !checkCanView(&g.Controller, uid) {
+		return
+	}
 	graphData, err := models.GetActivityGraph(uid)
 	if err != nil {
 		hub := sentry.GetHubFromContext(g.Ctx.Request.Context())
@@ -58,6 +72,7 @@ func (g *GraphController) GetActivityGraph() {
 // @Success 200 {object} types.StatusCounts
 // @Failure 401 : Unauthorized
 // @Failure 400 :uid is invalid
+// @Failure 403 profile is private
 // @Failure 404 user not found
 // @Failure 500 server_error
 // @router /status [get]
@@ -75,6 +90,9 @@ func (g *GraphController) GetStatusCounts() {
 		g.ServeJSON()
 		return
 	}
+	if !checkCanView(&g.Controller, uid) {
+		return
+	}
 	status, err := models.GetStatusCounts(uid)
 	if err != nil {
 		hub := sentry.GetHubFromContext(g.Ctx.Request.Context())
@@ -87,3 +105,60 @@ func (g *GraphController) GetStatusCounts() {
 	g.Data["json"] = status
 	g.ServeJSON()
 }
+
+// @Title Rating Graph
+// @Description Gives the contest rating history on a site for a user with given uid, (Logged-in user if uid is empty)
+// @Security token_auth read:user
+// @Param	site	path 	string	true		"site name (codeforces, codechef, leetcode, atcoder)"
+// @Param	uid		path 	string	false		"uid of user"
+// @Success 200 {object} types.RatingHistory
+// @Failure 401 : Unauthorized
+// @Failure 400 :uid or site is invalid
+// @Failure 403 profile is private
+// @Failure 404 user not found
+// @Failure 500 server_error
+// @router /rating/:site [get]
+// @router /rating/:site/:uid [get]
+func (g *GraphController) GetRatingGraph() {
+	site := g.GetString(":site")
+	if s, ok := scrappers.Lookup(site); !ok || !s.HasRatingHistory() {
+		g.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		g.Data["json"] = BadInputError("Rating history not available for site")
+		g.ServeJSON()
+		return
+	}
+	uidString := g.GetString(":uid")
+	var uid bson.ObjectId
+	if bson.IsObjectIdHex(uidString) {
+		uid = bson.ObjectIdHex(uidString)
+	} else if uidString == "" {
+		uid = g.Ctx.Input.GetData("uid").(bson.ObjectId)
+	} else {
+		g.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		g.Data["json"] = BadInputError("Invalid UID")
+		g.ServeJSON()
+		return
+	}
+	if !checkCanView(&g.Controller, uid) {
+		return
+	}
+	history, err := models.GetRatingHistory(uid, site)
+	if err == mgo.ErrNotFound {
+		g.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		g.Data["json"] = NotFoundError("User not found")
+		g.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(g.Ctx.Request.Context())
+		hub.CaptureException(err)
+		g.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		g.Data["json"] = InternalServerError("Server error.. Please report to admin")
+		g.ServeJSON()
+		return
+	}
+	if history == nil {
+		history = types.RatingHistory{}
+	}
+	g.Data["json"] = history
+	g.ServeJSON()
+}
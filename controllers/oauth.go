@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/services/auth"
+	"github.com/mdg-iitr/Codephile/services/oauth"
+)
+
+// Returns the configured provider of the request. Writes the error
+// response and returns false if the provider is not supported.
+func (u *UserController) oauthProvider() (oauth.Provider, bool) {
+	provider, ok := oauth.Lookup(u.GetString(":provider"))
+	if !ok {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		u.Data["json"] = BadInputError("Invalid provider")
+		u.ServeJSON()
+	}
+	return provider, ok
+}
+
+// The provider redirects the user back to the callback endpoint after the login
+func (u *UserController) oauthRedirectURL(provider oauth.Provider) string {
+	var hostName string
+	if u.Ctx.Request.TLS == nil {
+		hostName = "http://" + u.Ctx.Request.Host
+	} else {
+		hostName = "https://" + u.Ctx.Request.Host
+	}
+	return hostName + "/v1/user/oauth/" + provider.Name + "/callback"
+}
+
+// Cookie holding the secret binding a login on a provider to the browser
+// which started it
+const oauthBindingCookie = "oauth_binding"
+
+func (u *UserController) setOAuthBinding(binding string) {
+	http.SetCookie(u.Ctx.ResponseWriter, &http.Cookie{
+		Name:     oauthBindingCookie,
+		Value:    binding,
+		Path:     "/v1/user/oauth/",
+		MaxAge:   int(oauth.StateTTL.Seconds()),
+		Secure:   u.Ctx.Request.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Ends the login on the provider by redirecting the browser to the client,
+// passing the result in the fragment so that it doesn't reach any server
+func (u *UserController) oauthDone(result url.Values) {
+	http.SetCookie(u.Ctx.ResponseWriter, &http.Cookie{
+		Name:   oauthBindingCookie,
+		Path:   "/v1/user/oauth/",
+		MaxAge: -1,
+	})
+	u.Redirect(oauth.ClientURL()+"#"+result.Encode(), http.StatusFound)
+}
+
+func (u *UserController) oauthFailed(reason string) {
+	u.oauthDone(url.Values{"error": {reason}})
+}
+
+func (u *UserController) oauthServerError(err error) {
+	hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+	hub.CaptureException(err)
+	log.Println(err.Error())
+	u.oauthFailed("server_error")
+}
+
+// @Title OAuth Login
+// @Description Redirects to the login page of the provider. The provider redirects back to the callback endpoint, which logs the user in
+// @Param	provider		path 	string	true		"google or github"
+// @Success 302 redirect to the provider
+// @Failure 400 invalid provider
+// @Failure 500 server_error
+// @router /oauth/:provider/login [get]
+func (u *UserController) OAuthLogin() {
+	provider, ok := u.oauthProvider()
+	if !ok {
+		return
+	}
+	state, binding, err := oauth.NewState(provider.Name, "")
+	if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.setOAuthBinding(binding)
+	u.Redirect(provider.AuthCodeURL(u.oauthRedirectURL(provider), state), http.StatusFound)
+}
+
+// @Title OAuth Link
+// @Description Returns the url of the login page of the provider, to link the account on the provider to the logged in user. Sets a cookie which must be present when the provider redirects back, so the request must be made with credentials from the browser opening the url
+// @Security token_auth write:user
+// @Param	provider		path 	string	true		"google or github"
+// @Success 200 {string} url of the login page
+// @Failure 400 invalid provider
+// @Failure 401 invalid authentication token
+// @Failure 403 request made with an API key
+// @Failure 500 server_error
+// @router /oauth/:provider/link [post]
+func (u *UserController) OAuthLink() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	provider, ok := u.oauthProvider()
+	if !ok {
+		return
+	}
+	state, binding, err := oauth.NewState(provider.Name, uid.Hex())
+	if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.setOAuthBinding(binding)
+	u.Data["json"] = map[string]string{"url": provider.AuthCodeURL(u.oauthRedirectURL(provider), state)}
+	u.ServeJSON()
+}
+
+// @Title OAuth Callback
+// @Description Completes the login on the provider. Logs the user in, signing up if needed, or links the account on the provider to the user who asked to. Redirects to the client with the tokens, status=linked or error in the fragment of the url
+// @Param	provider		path 	string	true		"google or github"
+// @Param	code		query 	string	true		"code given by the provider"
+// @Param	state		query 	string	true		"state given by the provider"
+// @Success 302 redirect to the client
+// @Failure 400 invalid provider
+// @router /oauth/:provider/callback [get]
+func (u *UserController) OAuthCallback() {
+	provider, ok := u.oauthProvider()
+	if !ok {
+		return
+	}
+	var binding string
+	if cookie, err := u.Ctx.Request.Cookie(oauthBindingCookie); err == nil {
+		binding = cookie.Value
+	}
+	uid, err := oauth.ConsumeState(provider.Name, u.GetString("state"), binding)
+	if err == OAuthStateInvalidError {
+		u.oauthFailed("invalid_state")
+		return
+	} else if err != nil {
+		u.oauthServerError(err)
+		return
+	}
+	identity, err := provider.Exchange(u.Ctx.Request.Context(), u.oauthRedirectURL(provider), u.GetString("code"))
+	if err != nil {
+		log.Println(err.Error())
+		u.oauthFailed("provider_login_failed")
+		return
+	}
+
+	if uid != "" {
+		err = models.LinkOAuth(bson.ObjectIdHex(uid), provider.Name, identity)
+		if err == OAuthAlreadyLinkedError {
+			u.oauthFailed("linked_to_another_user")
+			return
+		} else if err != nil {
+			u.oauthServerError(err)
+			return
+		}
+		u.oauthDone(url.Values{"status": {"linked"}})
+		return
+	}
+
+	user, err := models.LoginWithOAuth(provider.Name, identity)
+	if err == FieldEmptyError {
+		u.oauthFailed("email_not_shared")
+		return
+	} else if err == UserAlreadyExistError {
+		u.oauthFailed("email_already_exists")
+		return
+	} else if err != nil {
+		u.oauthServerError(err)
+		return
+	}
+	tokens, err := auth.CreateSession(user.ID.Hex(), u.Ctx.Request.UserAgent(), u.Ctx.Input.IP(), userScopes(user.ID))
+	if err != nil {
+		u.oauthServerError(err)
+		return
+	}
+	u.oauthDone(url.Values{
+		"token":         {tokens.AccessToken},
+		"refresh_token": {tokens.RefreshToken},
+		"expires_in":    {strconv.FormatInt(tokens.ExpiresIn, 10)},
+	})
+}
+
+// @Title OAuth Unlink
+// @Description Unlinks the account on the provider from the logged in user
+// @Security token_auth write:user
+// @Param	provider		path 	string	true		"google or github"
+// @Success 200 {string} provider unlinked
+// @Failure 400 invalid provider
+// @Failure 401 invalid authentication token
+// @Failure 403 request made with an API key
+// @Failure 409 no other way to log in
+// @Failure 500 server_error
+// @router /oauth/:provider [delete]
+func (u *UserController) OAuthUnlink() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	provider, ok := u.oauthProvider()
+	if !ok {
+		return
+	}
+	err := models.UnlinkOAuth(uid, provider.Name)
+	if err == LastLoginMethodError {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusConflict)
+		u.Data["json"] = AlreadyExistsError("Set a password or link another provider first")
+		u.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = map[string]string{"status": "Provider unlinked"}
+	u.ServeJSON()
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/types"
+)
+
+// Page size of the paginated endpoints, unless asked otherwise
+const defaultPageSize = 100
+
+// Largest page size the paginated endpoints serve
+const maxPageSize = 200
+
+// Parses the cursor and the page size of the paginated endpoints. Without
+// a cursor, the page starts from the time given by before, the current
+// time by default. Writes the error response and returns false if the
+// params are invalid.
+func parsePage(c *beego.Controller) (types.Cursor, int, bool) {
+	count, err := c.GetInt("count", defaultPageSize)
+	if err != nil || count <= 0 || count > maxPageSize {
+		c.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		c.Data["json"] = errors.BadInputError("Invalid count")
+		c.ServeJSON()
+		return types.Cursor{}, 0, false
+	}
+	if s := c.GetString("cursor"); s != "" {
+		cursor, err := types.ParseCursor(s)
+		if err != nil {
+			c.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+			c.Data["json"] = errors.BadInputError("Invalid cursor")
+			c.ServeJSON()
+			return types.Cursor{}, 0, false
+		}
+		return cursor, count, true
+	}
+	before, err := c.GetInt64("before", time.Now().UTC().Unix())
+	if err != nil {
+		c.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		c.Data["json"] = errors.BadInputError("Invalid query param value")
+		c.ServeJSON()
+		return types.Cursor{}, 0, false
+	}
+	if before == 0 {
+		before = time.Now().UTC().Unix()
+	}
+	return types.Cursor{Time: time.Unix(before, 0)}, count, true
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/astaxie/beego"
+	"github.com/getsentry/sentry-go"
+	"github.com/globalsign/mgo/bson"
+	"github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models"
+)
+
+// Checks whether the requesting user can see the profile and the activity
+// of the user with the given uid, as per the privacy setting of the user.
+// Writes the error response and returns false if not.
+func checkCanView(c *beego.Controller, uid bson.ObjectId) bool {
+	viewer := c.Ctx.Input.GetData("uid").(bson.ObjectId)
+	ok, err := models.CanView(viewer, uid)
+	if err == errors.UserNotFoundError {
+		c.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		c.Data["json"] = errors.NotFoundError("User not found")
+		c.ServeJSON()
+		return false
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(c.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		c.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		c.Data["json"] = errors.InternalServerError("Internal server error")
+		c.ServeJSON()
+		return false
+	}
+	if !ok {
+		c.Ctx.ResponseWriter.WriteHeader(http.StatusForbidden)
+		c.Data["json"] = errors.ForbiddenError("Profile is private")
+		c.ServeJSON()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/astaxie/beego"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/types"
+)
+
+// Scope required by each endpoint, keyed by controller and method. Keep in
+// sync with the @Security annotations of the endpoints. Endpoints left out
+// need no scope.
+var routeScopes = map[string]map[string]string{
+	"UserController": {
+		"CreateAPIKey":        "write:user",
+		"GetAPIKeys":          "read:user",
+		"DeleteAPIKey":        "write:user",
+		"OAuthLink":           "write:user",
+		"OAuthUnlink":         "write:user",
+		"Search":              "read:user",
+		"GetAll":              "read:user",
+		"Get":                 "read:user",
+		"Put":                 "write:user",
+		"Sessions":            "read:user",
+		"RevokeSession":       "write:user",
+		"Logout":              "write:user",
+		"Verify":              "read:user",
+		"IssueOwnershipToken": "write:user",
+		"ConfirmOwnership":    "write:user",
+		"Fetch":               "write:user",
+		"FetchStatus":         "read:user",
+		"ReturnAllProfiles":   "read:user",
+		"ProfilePic":          "write:user",
+		"PasswordChange":      "write:user",
+		"FilterUsers":         "read:user",
+	},
+	"SubmissionController": {
+		"GetAllSubmissions":    "read:submission",
+		"PaginatedSubmissions": "read:submission",
+		"SaveSubmission":       "write:submission",
+		"FilterSubmission":     "read:submission",
+	},
+	"FeedController": {
+		"ContestsFeed":  "read:feed",
+		"AllFeed":       "read:feed",
+		"PaginatedFeed": "read:feed",
+	},
+	"FriendsController": {
+		"FollowUser":          "write:follow",
+		"UnFollowUser":        "write:follow",
+		"CompareUser":         "read:follow",
+		"GetFollowing":        "read:follow",
+		"GetFollowers":        "read:follow",
+		"GetRecommendations":  "read:follow",
+		"GetFollowRequests":   "read:follow",
+		"AcceptFollowRequest": "write:follow",
+		"RejectFollowRequest": "write:follow",
+		"BlockUser":           "write:follow",
+		"UnblockUser":         "write:follow",
+		"MuteUser":            "write:follow",
+		"UnmuteUser":          "write:follow",
+		"GetBlockedUsers":     "read:follow",
+		"GetMutedUsers":       "read:follow",
+	},
+	"GraphController": {
+		"GetActivityGraph": "read:user",
+		"GetStatusCounts":  "read:user",
+		"GetRatingGraph":   "read:user",
+	},
+	"ContestController": {
+		"GetContests":                "read:contests",
+		"GetSpecificContests":        "read:contests",
+		"GetParticipations":          "read:contests",
+		"GetFollowingParticipations": "read:contests",
+	},
+	"RankController": {
+		"CodechefRank":   "read:user",
+		"CodeforcesRank": "read:user",
+	},
+	"AdminController": {
+		"SchedulerStatus": "admin",
+		"PauseScheduler":  "admin",
+		"ResumeScheduler": "admin",
+		"Queue":           "admin",
+		"LookupUser":      "admin",
+		"BlacklistUser":   "admin",
+		"WhitelistUser":   "admin",
+		"DeleteUser":      "admin",
+		"RefreshUser":     "admin",
+		"GrantAdmin":      "admin",
+		"RevokeAdmin":     "admin",
+		"AuditLog":        "admin",
+	},
+}
+
+// RouteScope returns the scope required by the method of the controller,
+// empty if the endpoint needs none
+func RouteScope(controller string, method string) string {
+	return routeScopes[controller][method]
+}
+
+// Endpoints managing the account itself: its details, logins, sessions and
+// keys. They are reached only with the access tokens of the user, whatever
+// the scopes of an API key, so that a leaked key can't take over the
+// account.
+var accountRoutes = map[string]map[string]bool{
+	"UserController": {
+		"CreateAPIKey":        true,
+		"GetAPIKeys":          true,
+		"DeleteAPIKey":        true,
+		"Sessions":            true,
+		"RevokeSession":       true,
+		"Logout":              true,
+		"Put":                 true,
+		"PasswordChange":      true,
+		"ProfilePic":          true,
+		"OAuthLink":           true,
+		"OAuthUnlink":         true,
+		"IssueOwnershipToken": true,
+		"ConfirmOwnership":    true,
+	},
+}
+
+// Checks whether the token of the request is granted the scope required
+// by the endpoint, and that account endpoints aren't reached with an API
+// key. Writes the error response and returns false if not.
+func checkScope(c *beego.Controller) bool {
+	controller, method := c.GetControllerAndAction()
+	if isAPIKey, _ := c.Ctx.Input.GetData("apikey").(bool); isAPIKey && accountRoutes[controller][method] {
+		c.Ctx.ResponseWriter.WriteHeader(http.StatusForbidden)
+		c.Data["json"] = ForbiddenError("Not allowed with an API key")
+		c.ServeJSON()
+		return false
+	}
+	scope, ok := routeScopes[controller][method]
+	if !ok {
+		return true
+	}
+	// Endpoints open to all are reached without a token
+	granted, ok := c.Ctx.Input.GetData("scopes").([]string)
+	if !ok {
+		return true
+	}
+	for _, s := range granted {
+		if s == scope {
+			return true
+		}
+	}
+	c.Ctx.ResponseWriter.WriteHeader(http.StatusForbidden)
+	c.Data["json"] = ForbiddenError("Token lacks the scope " + scope)
+	c.ServeJSON()
+	return false
+}
+
+// Reports whether the user has the admin role. The users listed in
+// ADMIN_UIDS are admins too, so that the first admin can be made.
+func isAdmin(uid bson.ObjectId) bool {
+	for _, admin := range beego.AppConfig.Strings("ADMIN_UIDS") {
+		if admin != "" && admin == uid.Hex() {
+			return true
+		}
+	}
+	admin, err := models.IsAdmin(uid)
+	if err != nil {
+		log.Println(err.Error())
+	}
+	return admin
+}
+
+// Returns the scopes granted to the tokens of the user
+func userScopes(uid bson.ObjectId) []string {
+	scopes := append([]string{}, types.Scopes...)
+	if isAdmin(uid) {
+		scopes = append(scopes, types.ScopeAdmin)
+	}
+	return scopes
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"github.com/getsentry/sentry-go"
+	"github.com/globalsign/mgo/bson"
 	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models"
 	"log"
@@ -32,7 +33,8 @@ func (u *UserController) Search() {
 	if err != nil {
 		c = 500
 	}
-	results, err := models.SearchUser(query, c)
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	results, err := models.SearchUser(uid, query, c)
 	if err != nil {
 		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
 		hub.CaptureException(err)
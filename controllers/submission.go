@@ -5,25 +5,32 @@ import (
 	"github.com/getsentry/sentry-go"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
-	. "github.com/mdg-iitr/Codephile/conf"
 	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/scrappers"
 	"github.com/mdg-iitr/Codephile/services/worker"
 	"log"
 	"net/http"
-	"time"
 )
 
 type SubmissionController struct {
 	beego.Controller
 }
 
+// Prepare rejects tokens lacking the scope of the endpoint
+func (s *SubmissionController) Prepare() {
+	if !checkScope(&s.Controller) {
+		s.StopRun()
+	}
+}
+
 // @Title All submissions
 // @Description Get all submissions of a user(logged-in if uid is empty) across various platforms
 // @Security token_auth read:submission
 // @Param	uid		path 	string	false		"UID of user"
 // @Success 200 {object} []types.Submission
 // @Failure 400 invalid uid
+// @Failure 403 profile is private
 // @Failure 404 User/Submission not found
 // @router /all [get]
 // @router /all/:uid [get]
@@ -40,6 +47,9 @@ func (s *SubmissionController) GetAllSubmissions() {
 		s.ServeJSON()
 		return
 	}
+	if !checkCanView(&s.Controller, uid) {
+		return
+	}
 	subs, err := models.GetAllSubmissions(uid)
 	if err == mgo.ErrNotFound {
 		s.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
@@ -61,12 +71,15 @@ func (s *SubmissionController) GetAllSubmissions() {
 }
 
 // @Title Get Submissions
-// @Description Get paginated submissions(100 per page) of user(logged-in if uid is empty) across various platforms
+// @Description Get paginated submissions(100 per page by default) of user(logged-in if uid is empty) across various platforms, along with the cursor of the next page
 // @Security token_auth read:submission
 // @Param	uid		path 	string	false		"UID of user"
-// @Param	before		query 	string	true  "Time before which submissions to be returned, uses current time if empty or not present"
-// @Success 200 {object} []types.Submission
-// @Failure 400 invalid uid
+// @Param	cursor		query 	string	false  "Cursor of the page, next_cursor of the previous page. Starts from before if empty"
+// @Param	before		query 	string	false  "Time before which submissions to be returned, uses current time if empty or not present"
+// @Param	count		query 	int	false  "No of submissions in the page, at most 200"
+// @Success 200 {object} types.SubmissionPage
+// @Failure 400 invalid uid, cursor, before or count
+// @Failure 403 profile is private
 // @Failure 404 User/Submission not found
 // @router / [get]
 // @router /:uid [get]
@@ -83,17 +96,14 @@ func (s *SubmissionController) PaginatedSubmissions() {
 		s.ServeJSON()
 		return
 	}
-	before, err := s.GetInt64("before", time.Now().UTC().Unix())
-	if err != nil {
-		s.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
-		s.Data["json"] = BadInputError("Invalid query param value")
-		s.ServeJSON()
+	if !checkCanView(&s.Controller, uid) {
 		return
 	}
-	if before == 0 {
-		before = time.Now().UTC().Unix()
+	cursor, count, ok := parsePage(&s.Controller)
+	if !ok {
+		return
 	}
-	feed, err := models.GetSubmissions(uid, time.Unix(before, 0))
+	feed, err := models.GetSubmissions(uid, cursor, count)
 	if err == mgo.ErrNotFound {
 		s.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
 		s.Data["json"] = NotFoundError("User not found")
@@ -124,7 +134,7 @@ func (s *SubmissionController) PaginatedSubmissions() {
 func (s *SubmissionController) SaveSubmission() {
 	uid := s.Ctx.Input.GetData("uid").(bson.ObjectId)
 	site := s.GetString(":site")
-	if !IsSiteValid(site) {
+	if !scrappers.IsSiteValid(site) {
 		s.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
 		s.Data["json"] = BadInputError("Invalid contest site")
 		s.ServeJSON()
@@ -151,8 +161,9 @@ func (s *SubmissionController) SaveSubmission() {
 // @Param	site		path 	string	true		"Website name"
 // @Param	status		query 	string	false		"Submission status"
 // @Param	tag 		query	string	false		"Submission tag"
-// @Success 200 {object} types.CodechefSubmission
-// @Failure 400 user not exist
+// @Success 200 {object} []types.Submission
+// @Failure 400 invalid uid or site
+// @Failure 403 profile is private
 // @Failure 500 server_error
 // @router /:site/filter [get]
 // @router /:site/:uid/filter [get]
@@ -169,9 +180,18 @@ func (s *SubmissionController) FilterSubmission() {
 		s.ServeJSON()
 		return
 	}
+	if !checkCanView(&s.Controller, uid) {
+		return
+	}
 	status := s.GetString("status")
 	site := s.GetString(":site")
 	tag := s.GetString("tag")
+	if !scrappers.IsSiteValid(site) {
+		s.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		s.Data["json"] = BadInputError("Invalid site")
+		s.ServeJSON()
+		return
+	}
 	subs, err := models.FilterSubmission(uid, status, tag, site)
 	if err != nil {
 		hub := sentry.GetHubFromContext(s.Ctx.Request.Context())
@@ -18,7 +18,6 @@ import (
 	"github.com/getsentry/sentry-go"
 	"github.com/globalsign/mgo/bson"
 	"github.com/gorilla/schema"
-	. "github.com/mdg-iitr/Codephile/conf"
 	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models"
 	"github.com/mdg-iitr/Codephile/models/types"
@@ -36,6 +35,13 @@ type UserController struct {
 	beego.Controller
 }
 
+// Prepare rejects tokens lacking the scope of the endpoint
+func (u *UserController) Prepare() {
+	if !checkScope(&u.Controller) {
+		u.StopRun()
+	}
+}
+
 // @Title CreateUser
 // @Description create users
 // @Param	username 			formData	string	true "Username"
@@ -43,11 +49,7 @@ type UserController struct {
 // @Param	password			formData 	string	true "Password"
 // @Param	fullname			formData 	string	true "Full name of User"
 // @Param	institute			formData 	string	false "Name of Institute"
-// @Param	handle.codechef		formData	string 	false "Codechef Handle"
-// @Param	handle.codeforces	formData	string 	false "Codeforces Handle"
-// @Param	handle.hackerrank	formData	string 	false "Hackerrank Handle"
-// @Param	handle.spoj			formData	string 	false "Spoj Handle"
-// @Param	handle.leetcode		formData	string 	false "Leetcode Handle"
+// @Param	handle.<site>		formData	string 	false "Handle on the site, one param per supported site e.g. handle.codeforces"
 // @Success 201 {int} types.User.Id
 // @Failure 409 username already exists
 // @Failure 400 bad request body or blank username/password/full name
@@ -144,6 +146,7 @@ func (u *UserController) GetAll() {
 // @Success 200 {object} types.User
 // @Failure 401 : Unauthorized
 // @Failure 400 :uid is invalid
+// @Failure 403 profile is private
 // @Failure 404 user not found
 // @Failure 500 server_error
 // @router / [get]
@@ -161,6 +164,9 @@ func (u *UserController) Get() {
 		u.ServeJSON()
 		return
 	}
+	if !checkCanView(&u.Controller, uid) {
+		return
+	}
 	user, err := models.GetUser(uid)
 	if err != nil {
 		u.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
@@ -173,20 +179,18 @@ func (u *UserController) Get() {
 }
 
 // @Title Update
-// @Description update the logged in user
+// @Description update the logged in user. Changed handles are linked unverified, use the ownership verification to mark them verified. A handle verified by another user can only be linked that way.
 // @Security token_auth write:user
 // @Param	username 		formData	string	false "New Username"
 // @Param	fullname		formData 	string	false "New Full name of User"
 // @Param	institute		formData 	string	false "New Name of Institute"
-// @Param	handle.codechef	formData	string 	false "New Codechef Handle"
-// @Param	handle.codeforces	formData	string 	false "New Codeforces Handle"
-// @Param	handle.hackerrank	formData	string 	false "New Hackerrank Handle"
-// @Param	handle.spoj			formData	string 	false "New Spoj Handle"
-// @Param	handle.leetcode		formData	string 	false "New Leetcode Handle"
+// @Param	privacy			formData 	string	false "Who can see the profile: public, followers or private"
+// @Param	handle.<site>	formData	string 	false "New handle on the site, one param per supported site e.g. handle.codeforces"
 // @Success 202 {object} types.User
-// @Failure 409 username already exists
+// @Failure 409 username already exists or handle verified by another user
 // @Failure 400 bad request body
 // @Failure 401 : Unauthorized
+// @Failure 403 request made with an API key
 // @Failure 404 : User not found
 // @Failure 500 server_error
 // @router / [put]
@@ -199,12 +203,23 @@ func (u *UserController) Put() {
 		u.ServeJSON()
 		return
 	}
+	if newUser.Privacy != "" && !types.IsPrivacyValid(newUser.Privacy) {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		u.Data["json"] = BadInputError("Invalid privacy")
+		u.ServeJSON()
+		return
+	}
 	uu, err := models.UpdateUser(uid, &newUser, u.Ctx.Request.Context())
 	if err == UserAlreadyExistError {
 		u.Ctx.ResponseWriter.WriteHeader(http.StatusConflict)
 		u.Data["json"] = AlreadyExistsError("User already exists")
 		u.ServeJSON()
 		return
+	} else if err == HandleClaimedError {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusConflict)
+		u.Data["json"] = AlreadyExistsError("Handle verified by another user, prove the ownership to link it")
+		u.ServeJSON()
+		return
 	} else if err != nil {
 		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
 		hub.CaptureException(err)
@@ -223,7 +238,7 @@ func (u *UserController) Put() {
 // @Description Logs user into the system
 // @Param	username		formData 	string	true		"The username for login"
 // @Param	password		formData 	string	true		"The password for login"
-// @Success 200 {string} login success
+// @Success 200 {object} auth.Tokens
 // @Failure 401 wrong credentials
 // @Failure 403 email not verified
 // @router /login [post]
@@ -251,7 +266,102 @@ func (u *UserController) Login() {
 		u.ServeJSON()
 		return
 	}
-	u.Data["json"] = map[string]string{"token": auth.GenerateToken(user.ID.Hex())}
+	tokens, err := auth.CreateSession(user.ID.Hex(), u.Ctx.Request.UserAgent(), u.Ctx.Input.IP(), userScopes(user.ID))
+	if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = tokens
+	u.ServeJSON()
+}
+
+// @Title Refresh
+// @Description Issues new tokens for the session of the refresh token, with the scopes the user holds now. The refresh token can't be used again
+// @Param	refresh_token		formData 	string	true		"The refresh token of the session"
+// @Success 200 {object} auth.Tokens
+// @Failure 401 invalid or expired refresh token
+// @Failure 500 server_error
+// @router /refresh [post]
+func (u *UserController) Refresh() {
+	tokens, err := auth.RefreshSession(u.Ctx.Request.FormValue("refresh_token"), func(uid string) []string {
+		return userScopes(bson.ObjectIdHex(uid))
+	})
+	if err == InvalidRefreshTokenError {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusUnauthorized)
+		u.Data["json"] = UnauthorizedError("Invalid refresh token")
+		u.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = tokens
+	u.ServeJSON()
+}
+
+// @Title Sessions
+// @Description Lists the devices the logged in user is logged in on, latest refreshed first
+// @Security token_auth read:user
+// @Success 200 {object} []auth.Session
+// @Failure 401 invalid authentication token
+// @Failure 403 request made with an API key
+// @Failure 500 server_error
+// @router /sessions [get]
+func (u *UserController) Sessions() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	sessionID, _ := u.Ctx.Input.GetData("session").(string)
+	sessions, err := auth.GetSessions(uid.Hex(), sessionID)
+	if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = sessions
+	u.ServeJSON()
+}
+
+// @Title Revoke Session
+// @Description Logs the logged in user out of the session with the given id
+// @Security token_auth write:user
+// @Param	sid		path 	string	true		"id of the session"
+// @Success 200 {string} session revoked
+// @Failure 401 invalid authentication token
+// @Failure 403 request made with an API key
+// @Failure 404 session not found
+// @Failure 500 server_error
+// @router /sessions/:sid [delete]
+func (u *UserController) RevokeSession() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	err := auth.RevokeSession(uid.Hex(), u.GetString(":sid"))
+	if err == SessionNotFoundError {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		u.Data["json"] = NotFoundError("Session not found")
+		u.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = map[string]string{"status": "Session revoked"}
 	u.ServeJSON()
 }
 
@@ -329,6 +439,7 @@ func (u *UserController) PasswordResetForm() {
 // @Security token_auth write:user
 // @Success 200 {string} logout success
 // @Failure 401 invalid authentication token
+// @Failure 403 request made with an API key
 // @Failure 500 server_error
 // @router /logout [post]
 func (u *UserController) Logout() {
@@ -350,7 +461,12 @@ func (u *UserController) Logout() {
 		return
 	}
 	if requestToken.Valid && !auth.IsTokenExpired(requestToken) {
-		err := auth.BlacklistToken(requestToken)
+		if sessionID := auth.SessionID(requestToken); sessionID != "" {
+			uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+			err = auth.RevokeSession(uid.Hex(), sessionID)
+		} else {
+			err = auth.BlacklistToken(requestToken)
+		}
 		if err != nil {
 			hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
 			hub.CaptureException(err)
@@ -378,6 +494,11 @@ func (u *UserController) parseRequestBody() (types.User, error) {
 	} else {
 		decoder.IgnoreUnknownKeys(true)
 		err = decoder.Decode(&user, u.Ctx.Request.PostForm)
+		for _, site := range scrappers.Sites() {
+			if handle := u.Ctx.Request.PostForm.Get("handle." + site.Name); handle != "" {
+				user.Handle.Set(site.Name, handle)
+			}
+		}
 	}
 	if err != nil {
 		log.Println(err.Error())
@@ -419,6 +540,90 @@ func (u *UserController) Verify() {
 	u.ServeJSON()
 }
 
+// @Title Issue Ownership Token
+// @Description Issues the token which the logged in user places on the site to prove the ownership of the handle. Codeforces checks the first name, last name and organization, or a compilation error submitted to the returned problem in the returned language. Codechef checks the full name and organization, LeetCode the name and summary.
+// @Security token_auth write:user
+// @Param	site		path 	string	true		"site name"
+// @Param	handle		query 	string	true		"handle to link"
+// @Success 201 {object} types.OwnershipChallenge
+// @Failure 400 invalid site or handle, or site doesn't support verification
+// @Failure 401 Unauthenticated
+// @Failure 403 request made with an API key
+// @Failure 500 server_error
+// @router /verify/:site/token [post]
+func (u *UserController) IssueOwnershipToken() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	site := u.GetString(":site")
+	handle := u.GetString("handle")
+	if !scrappers.IsSiteValid(site) || handle == "" {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		u.Data["json"] = BadInputError("Invalid contest site or handle")
+		u.ServeJSON()
+		return
+	}
+	challenge, err := models.IssueOwnershipToken(uid, site, handle, u.Ctx.Request.Context())
+	if err == OwnershipUnsupportedError {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
+		u.Data["json"] = BadInputError(err.Error())
+		u.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Ctx.ResponseWriter.WriteHeader(http.StatusCreated)
+	u.Data["json"] = challenge
+	u.ServeJSON()
+}
+
+// @Title Confirm Ownership
+// @Description Checks the token issued for the site and links the handle to the logged in user as verified
+// @Security token_auth write:user
+// @Param	site		path 	string	true		"site name"
+// @Success 200 Handle verified
+// @Failure 401 Unauthenticated
+// @Failure 403 token not found on the site or request made with an API key
+// @Failure 404 token expired or not issued
+// @Failure 503 site unavailable
+// @router /verify/:site/confirm [post]
+func (u *UserController) ConfirmOwnership() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	site := u.GetString(":site")
+	owned, err := models.ConfirmOwnership(uid, site, u.Ctx.Request.Context())
+	if err == OwnershipTokenNotFoundError {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
+		u.Data["json"] = NotFoundError(err.Error())
+		u.ServeJSON()
+		return
+	} else if _, ok := ScrapeErrorKindOf(err); ok {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+		u.Data["json"] = UnavailableError(err.Error())
+		u.ServeJSON()
+		return
+	} else if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	if !owned {
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusForbidden)
+		u.Data["json"] = ForbiddenError("Token not found on the site")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = map[string]string{"status": "Handle verified"}
+	u.ServeJSON()
+}
+
 // @Title Fetch User Info
 // @Description Fetches user info from different websites and store them into the database
 // @Security token_auth write:user
@@ -431,7 +636,7 @@ func (u *UserController) Verify() {
 func (u *UserController) Fetch() {
 	site := u.GetString(":site")
 	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
-	if !IsSiteValid(site) {
+	if !scrappers.IsSiteValid(site) {
 		u.Ctx.ResponseWriter.WriteHeader(http.StatusBadRequest)
 		u.Data["json"] = BadInputError("Invalid contest site")
 		u.ServeJSON()
@@ -450,6 +655,38 @@ func (u *UserController) Fetch() {
 	u.ServeJSON()
 }
 
+// @Title Fetch Status
+// @Description Returns the result of the latest fetch of every site for which the logged in user has set a handle
+// @Security token_auth read:user
+// @Success 200 {object} types.FetchStatuses
+// @Failure 401 Unauthenticated
+// @Failure 500 server_error
+// @router /fetch/status [get]
+func (u *UserController) FetchStatus() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
+	statuses, err := models.GetFetchStatus(uid)
+	if err == nil {
+		for site, status := range statuses {
+			status.Queued, err = worker.IsQueued(uid, site)
+			if err != nil {
+				break
+			}
+			statuses[site] = status
+		}
+	}
+	if err != nil {
+		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
+		hub.CaptureException(err)
+		log.Println(err.Error())
+		u.Ctx.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		u.Data["json"] = InternalServerError("Internal server error")
+		u.ServeJSON()
+		return
+	}
+	u.Data["json"] = statuses
+	u.ServeJSON()
+}
+
 // @Title Fetch All User Profiles And returns them
 // @Description Returns info of user(logged in user if uid is empty) from different websites
 // @Security token_auth read:user
@@ -457,6 +694,7 @@ func (u *UserController) Fetch() {
 // @Success 200 {object} types.AllProfiles
 // @Failure 401 Unauthenticated
 // @Failure 400 invalid user
+// @Failure 403 profile is private
 // @Failure 500 server_error
 // @router /fetch/ [get]
 // @router /fetch/:uid [get]
@@ -473,6 +711,9 @@ func (u *UserController) ReturnAllProfiles() {
 		u.ServeJSON()
 		return
 	}
+	if !checkCanView(&u.Controller, uid) {
+		return
+	}
 	user, err := models.GetProfiles(uid)
 	if err != nil {
 		u.Ctx.ResponseWriter.WriteHeader(http.StatusNotFound)
@@ -491,6 +732,7 @@ func (u *UserController) ReturnAllProfiles() {
 // @Param	image		formData 	file	true		"profile image"
 // @Success 201  successful
 // @Failure 401 Unauthenticated
+// @Failure 403 request made with an API key
 // @Failure 400 could not get image
 // @router /picture [put]
 func (u *UserController) ProfilePic() {
@@ -575,7 +817,7 @@ func (u *UserController) IsAvailable() {
 // @Success 200 {string} success
 // @Failure 401 Unauthenticated
 // @Failure 400 bad request
-// @Failure 403 old password incorrect
+// @Failure 403 old password incorrect or request made with an API key
 // @Failure 500 server error
 // @router /password-reset [post]
 func (u *UserController) PasswordChange() {
@@ -616,8 +858,9 @@ func (u *UserController) PasswordChange() {
 // @Failure 500 server_error
 // @router /filter [get]
 func (u *UserController) FilterUsers() {
+	uid := u.Ctx.Input.GetData("uid").(bson.ObjectId)
 	instituteName := u.GetString("institute")
-	res, err := models.FilterUsers(instituteName)
+	res, err := models.FilterUsers(uid, instituteName)
 	if err != nil {
 		hub := sentry.GetHubFromContext(u.Ctx.Request.Context())
 		hub.CaptureException(err)
@@ -14,6 +14,32 @@ var HandleNotFoundError = errors.New("handle not available")
 
 var ErrJobQueueFull = errors.New("Job queue completely full")
 
+var ErrJobAlreadyQueued = errors.New("job already queued")
+
 var FieldEmptyError = errors.New("empty field forbidden")
 
-var UserUnverifiedError = errors.New("E-mail not verified")
\ No newline at end of file
+var UserUnverifiedError = errors.New("E-mail not verified")
+
+var OwnershipUnsupportedError = errors.New("ownership verification not supported for the site")
+
+var OwnershipTokenNotFoundError = errors.New("ownership token expired or not issued")
+
+var HandleClaimedError = errors.New("handle verified by another user")
+
+var FollowRequestNotFoundError = errors.New("follow request not found")
+
+var UserBlockedError = errors.New("user blocked")
+
+var SessionNotFoundError = errors.New("session not found")
+
+var InvalidRefreshTokenError = errors.New("refresh token invalid or expired")
+
+var OAuthStateInvalidError = errors.New("oauth state invalid or expired")
+
+var OAuthAlreadyLinkedError = errors.New("account on the provider is linked to another user")
+
+var LastLoginMethodError = errors.New("the only way to log in can't be removed")
+
+var APIKeyNotFoundError = errors.New("api key not found")
+
+var APIKeyLimitError = errors.New("too many api keys")
\ No newline at end of file
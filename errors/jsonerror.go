@@ -31,9 +31,21 @@ func NotFoundError(error string) ErrorResponse {
 		Err:       error,
 	}
 }
+func ForbiddenError(error string) ErrorResponse {
+	return ErrorResponse{
+		ErrorType: "forbidden",
+		Err:       error,
+	}
+}
 func UnavailableError(error string) ErrorResponse {
 	return ErrorResponse{
 		ErrorType: "unavailable",
 		Err:       error,
 	}
 }
+func UnauthorizedError(error string) ErrorResponse {
+	return ErrorResponse{
+		ErrorType: "unauthorized",
+		Err:       error,
+	}
+}
@@ -0,0 +1,43 @@
+package errors
+
+import "fmt"
+
+// Kind of failure while scraping a site
+type ScrapeErrorKind string
+
+const (
+	// Handle doesn't exist on the site
+	ScrapeHandleNotFound ScrapeErrorKind = "handle_not_found"
+	// Site refused the request due to too many requests
+	ScrapeRateLimited ScrapeErrorKind = "rate_limited"
+	// Response of the site could not be understood, most likely because
+	// the site changed its API or pages
+	ScrapeUpstreamChanged ScrapeErrorKind = "upstream_changed"
+	// Site could not be reached
+	ScrapeNetwork ScrapeErrorKind = "network"
+)
+
+// ScrapeError is returned by the scrappers when the data of a user could
+// not be fetched from the site
+type ScrapeError struct {
+	Site string
+	Kind ScrapeErrorKind
+	Err  error
+}
+
+func (e *ScrapeError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Site, e.Kind, e.Err)
+}
+
+func NewScrapeError(site string, kind ScrapeErrorKind, err error) error {
+	return &ScrapeError{Site: site, Kind: kind, Err: err}
+}
+
+// ScrapeErrorKindOf returns the kind of err if it is a ScrapeError
+func ScrapeErrorKindOf(err error) (ScrapeErrorKind, bool) {
+	e, ok := err.(*ScrapeError)
+	if !ok {
+		return "", false
+	}
+	return e.Kind, true
+}
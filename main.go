@@ -3,7 +3,10 @@ package main
 import (
 	_ "github.com/mdg-iitr/Codephile/conf"
 	"github.com/astaxie/beego"
+	"github.com/mdg-iitr/Codephile/models"
 	_ "github.com/mdg-iitr/Codephile/routers"
+	"github.com/mdg-iitr/Codephile/services/scheduler"
+	"github.com/mdg-iitr/Codephile/services/worker"
 	sentryhttp "github.com/getsentry/sentry-go/http"
 	
 )
@@ -13,6 +16,13 @@ func main() {
 		beego.BConfig.WebConfig.DirectoryIndex = true
 		beego.BConfig.WebConfig.StaticDir["/docs"] = "swagger"
 	}
+	// Jobs left in queue by the previous run are picked up right away
+	worker.RegisterHandler(models.AddSubmissions)
+	worker.RegisterHandler(models.AddOrUpdateProfile)
+	worker.RegisterHandler(models.RefreshSite)
+	worker.SetReporter(models.RecordFetchResult)
+	worker.Start()
+	scheduler.Start()
 	sentryHandler := sentryhttp.New(sentryhttp.Options{
 		Repanic: true,
 	})
@@ -10,6 +10,7 @@ import (
 	"github.com/dgrijalva/jwt-go/request"
 	"github.com/getsentry/sentry-go"
 	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models"
 	"github.com/mdg-iitr/Codephile/services/auth"
 )
@@ -19,6 +20,8 @@ func Authenticate(ctx *context.Context) {
 	// signup and login endpoints
 	if (strings.HasPrefix(ctx.Request.RequestURI, "/v1/user/login") && ctx.Request.Method == "POST") ||
 		(strings.HasPrefix(ctx.Request.RequestURI, "/v1/user/signup") && ctx.Request.Method == "POST") ||
+		(strings.HasPrefix(ctx.Request.RequestURI, "/v1/user/refresh") && ctx.Request.Method == "POST") ||
+		(strings.HasPrefix(ctx.Request.RequestURI, "/v1/user/oauth/") && ctx.Request.Method == "GET") ||
 		(strings.HasPrefix(ctx.Request.RequestURI, "/v1/user/send-verify-email/") && ctx.Request.Method == "POST") ||
 		(strings.HasPrefix(ctx.Request.RequestURI, "/v1/user/confirm/") && ctx.Request.Method == "GET") ||
 		(strings.HasPrefix(ctx.Request.RequestURI, "/v1/user/password-reset-email") && ctx.Request.Method == "POST") ||
@@ -27,6 +30,10 @@ func Authenticate(ctx *context.Context) {
 		(strings.HasPrefix(ctx.Request.RequestURI, "/v1/user/verify") && ctx.Request.Method == "GET") {
 		return
 	}
+	if credential, err := request.OAuth2Extractor.ExtractToken(ctx.Request); err == nil && models.IsAPIKey(credential) {
+		authenticateAPIKey(ctx, credential)
+		return
+	}
 	requestToken, err := request.ParseFromRequest(ctx.Request, request.OAuth2Extractor, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unauthorized")
@@ -47,6 +54,9 @@ func Authenticate(ctx *context.Context) {
 			return
 		}
 		ctx.Input.SetData("uid", uid)
+		ctx.Input.SetData("session", auth.SessionID(requestToken))
+		ctx.Input.SetData("scopes", auth.TokenScopes(requestToken))
+		_ = models.MarkActive(uid)
 		if hub := sentry.GetHubFromContext(ctx.Request.Context()); hub != nil {
 			hub.ConfigureScope(func(scope *sentry.Scope) {
 				scope.SetUser(sentry.User{
@@ -59,3 +69,33 @@ func Authenticate(ctx *context.Context) {
 		_, _ = ctx.ResponseWriter.Write([]byte("401 Unauthorized\n"))
 	}
 }
+
+// Authenticates the request made with an API key, putting the scopes of
+// the key in context along with the uid
+func authenticateAPIKey(ctx *context.Context, key string) {
+	apiKey, err := models.AuthenticateAPIKey(key)
+	if err != nil {
+		if err != APIKeyNotFoundError {
+			sentry.GetHubFromContext(ctx.Request.Context()).CaptureException(err)
+		}
+		ctx.ResponseWriter.WriteHeader(401)
+		_, _ = ctx.ResponseWriter.Write([]byte("401 Unauthorized\n"))
+		return
+	}
+	if auth.IsUserBlacklisted(apiKey.UID) {
+		ctx.ResponseWriter.WriteHeader(401)
+		_, _ = ctx.ResponseWriter.Write([]byte("401 Unauthorized\n"))
+		return
+	}
+	ctx.Input.SetData("uid", apiKey.UID)
+	ctx.Input.SetData("scopes", apiKey.Scopes)
+	ctx.Input.SetData("apikey", true)
+	_ = models.MarkActive(apiKey.UID)
+	if hub := sentry.GetHubFromContext(ctx.Request.Context()); hub != nil {
+		hub.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetUser(sentry.User{
+				ID: apiKey.UID.Hex(),
+			})
+		})
+	}
+}
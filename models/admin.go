@@ -0,0 +1,197 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/services/auth"
+	"github.com/mdg-iitr/Codephile/services/firebase"
+)
+
+// IsAdmin reports whether the user has the admin role
+func IsAdmin(uid bson.ObjectId) (bool, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	n, err := sess.Collection.Find(bson.M{"_id": uid, "role": types.RoleAdmin}).Count()
+	return n > 0, err
+}
+
+// SetRole gives the role to the user, or takes the role away if role is
+// empty
+func SetRole(uid bson.ObjectId, role string) error {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	update := bson.M{"$set": bson.M{"role": role}}
+	if role == "" {
+		update = bson.M{"$unset": bson.M{"role": ""}}
+	}
+	err := sess.Collection.UpdateId(uid, update)
+	if err == mgo.ErrNotFound {
+		return UserNotFoundError
+	}
+	return err
+}
+
+// LookupUser returns the user with the given uid, username or email, as
+// shown to the admins. The blacklist status is not filled in.
+func LookupUser(key string) (*types.AdminUser, error) {
+	selector := bson.M{"$or": []bson.M{{"username": key}, {"email": key}}}
+	if bson.IsObjectIdHex(key) {
+		selector = bson.M{"_id": bson.ObjectIdHex(key)}
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	var user types.User
+	err := sess.Collection.Find(selector).Select(bson.M{"_id": 1, "username": 1, "email": 1,
+		"fullname": 1, "institute": 1, "verified": 1, "role": 1, "privacy": 1, "handle": 1,
+		"oauth": 1, "last_active": 1}).One(&user)
+	if err == mgo.ErrNotFound {
+		return nil, UserNotFoundError
+	} else if err != nil {
+		return nil, err
+	}
+	keySess := db.NewAPIKeyCollectionSession()
+	defer keySess.Close()
+	keys, err := keySess.Collection.Find(bson.M{"uid": user.ID}).Count()
+	if err != nil {
+		return nil, err
+	}
+	providers := []string{}
+	for provider := range user.OAuth {
+		providers = append(providers, provider)
+	}
+	return &types.AdminUser{
+		ID:         user.ID,
+		Username:   user.Username,
+		Email:      user.Email,
+		FullName:   user.FullName,
+		Institute:  user.Institute,
+		Verified:   user.Verified,
+		Role:       user.Role,
+		Privacy:    user.Privacy,
+		Handle:     user.Handle,
+		OAuth:      providers,
+		LastActive: user.LastActive,
+		APIKeys:    keys,
+	}, nil
+}
+
+// BlacklistUser bars the user from logging in, revoking the sessions of the
+// user. The action is recorded in the audit log first, so that it doesn't
+// go unrecorded. The admin is empty for the command line tools.
+func BlacklistUser(admin bson.ObjectId, uid bson.ObjectId, details map[string]string) error {
+	if err := AddAuditEntry(admin, types.AuditBlacklist, uid, details); err != nil {
+		return err
+	}
+	return auth.BlacklistUser(uid)
+}
+
+// WhitelistUser allows the blacklisted user to log in again, recording the
+// action in the audit log first
+func WhitelistUser(admin bson.ObjectId, uid bson.ObjectId, details map[string]string) error {
+	if err := AddAuditEntry(admin, types.AuditWhitelist, uid, details); err != nil {
+		return err
+	}
+	return auth.WhitelistUser(uid)
+}
+
+// DeleteUser removes the user along with the profile picture and all the
+// data of the user, and blocks the issued tokens. The action is recorded
+// in the audit log first, along with the username and email of the user.
+// Returns UserNotFoundError if the user doesn't exist
+func DeleteUser(admin bson.ObjectId, uid bson.ObjectId, details map[string]string) error {
+	user, err := LookupUser(uid.Hex())
+	if err != nil {
+		return err
+	}
+	recorded := map[string]string{"username": user.Username, "email": user.Email}
+	for k, v := range details {
+		recorded[k] = v
+	}
+	if err = AddAuditEntry(admin, types.AuditDeleteUser, uid, recorded); err != nil {
+		return err
+	}
+	if err = firebase.DeletePicture(GetPicture(uid)); err != nil {
+		return err
+	}
+	if err = deleteUserData(uid); err != nil {
+		return err
+	}
+	// Lastly block all the issued tokens
+	return auth.BlacklistUser(uid)
+}
+
+// Removes the user along with the submissions, participations, API keys,
+// follows, follow requests, blocks and mutes of the user
+func deleteUserData(uid bson.ObjectId) error {
+	if err := DeleteSubmissions(uid, ""); err != nil {
+		return err
+	}
+	if err := DeleteParticipations(uid, ""); err != nil {
+		return err
+	}
+	if err := DeleteAPIKeys(uid); err != nil {
+		return err
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	if err := coll.RemoveId(uid); err == mgo.ErrNotFound {
+		return UserNotFoundError
+	} else if err != nil {
+		return err
+	}
+	_, err := coll.UpdateAll(bson.M{"$or": []bson.M{
+		{"followingUsers.f_id": uid},
+		{"followRequests.f_id": uid},
+		{"blocked": uid},
+		{"muted": uid},
+	}}, bson.M{"$pull": bson.M{
+		"followingUsers": bson.M{"f_id": uid},
+		"followRequests": bson.M{"f_id": uid},
+		"blocked":        uid,
+		"muted":          uid,
+	}})
+	return err
+}
+
+// AddAuditEntry records the action taken by the admin on the target user,
+// which may be empty. The admin is empty for the command line tools.
+func AddAuditEntry(admin bson.ObjectId, action string, target bson.ObjectId, details map[string]string) error {
+	sess := db.NewAuditCollectionSession()
+	defer sess.Close()
+	return sess.Collection.Insert(types.AuditEntry{
+		ID:        bson.NewObjectId(),
+		Admin:     admin,
+		Action:    action,
+		Target:    target,
+		Details:   details,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// GetAuditLog returns the entries of the audit log after the cursor,
+// latest first, limited to the actions on the target if it is not empty
+func GetAuditLog(target bson.ObjectId, cursor types.Cursor, limit int) (types.AuditPage, error) {
+	sess := db.NewAuditCollectionSession()
+	defer sess.Close()
+	entries := []types.AuditEntry{}
+	filter := cursor.Filter()
+	if target != "" {
+		filter["target"] = target
+	}
+	err := sess.Collection.Find(filter).Sort("-created_at", "-_id").Limit(limit).All(&entries)
+	if err != nil {
+		return types.AuditPage{}, err
+	}
+	page := types.AuditPage{Data: entries}
+	if len(entries) == limit {
+		last := entries[len(entries)-1]
+		page.NextCursor = types.Cursor{Time: last.CreatedAt, ID: last.ID}.String()
+	}
+	return page, nil
+}
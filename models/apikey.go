@@ -0,0 +1,121 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+)
+
+// Prefix telling the API keys apart from the access tokens
+const APIKeyPrefix = "cpk_"
+
+// API keys a user can hold at a time
+const maxAPIKeys = 20
+
+// Last use of a key is recorded at most once in this interval
+const apiKeyLastUsedInterval = time.Minute
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey reports whether the credential looks like an API key
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
+
+// CreateAPIKey creates a key with the given scopes for the user. The
+// returned key can't be recovered later.
+// Returns APIKeyLimitError if the user holds too many keys
+func CreateAPIKey(uid bson.ObjectId, name string, scopes []string) (types.NewAPIKey, error) {
+	sess := db.NewAPIKeyCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	n, err := coll.Find(bson.M{"uid": uid}).Count()
+	if err != nil {
+		return types.NewAPIKey{}, err
+	} else if n >= maxAPIKeys {
+		return types.NewAPIKey{}, APIKeyLimitError
+	}
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return types.NewAPIKey{}, err
+	}
+	key := APIKeyPrefix + hex.EncodeToString(b)
+	apiKey := types.APIKey{
+		ID:        bson.NewObjectId(),
+		UID:       uid,
+		Name:      name,
+		Prefix:    key[:len(APIKeyPrefix)+8],
+		Hash:      hashAPIKey(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err = coll.Insert(apiKey); err != nil {
+		return types.NewAPIKey{}, err
+	}
+	return types.NewAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// GetAPIKeys returns the keys of the user, latest first
+func GetAPIKeys(uid bson.ObjectId) ([]types.APIKey, error) {
+	sess := db.NewAPIKeyCollectionSession()
+	defer sess.Close()
+	keys := []types.APIKey{}
+	err := sess.Collection.Find(bson.M{"uid": uid}).Sort("-created_at").All(&keys)
+	return keys, err
+}
+
+// DeleteAPIKey revokes the key of the user.
+// Returns APIKeyNotFoundError if the user has no such key
+func DeleteAPIKey(uid bson.ObjectId, id bson.ObjectId) error {
+	sess := db.NewAPIKeyCollectionSession()
+	defer sess.Close()
+	err := sess.Collection.Remove(bson.M{"_id": id, "uid": uid})
+	if err == mgo.ErrNotFound {
+		return APIKeyNotFoundError
+	}
+	return err
+}
+
+// AuthenticateAPIKey returns the key matching the given key and records
+// its use.
+// Returns APIKeyNotFoundError if the key is invalid or revoked
+func AuthenticateAPIKey(key string) (*types.APIKey, error) {
+	sess := db.NewAPIKeyCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	var apiKey types.APIKey
+	err := coll.Find(bson.M{"hash": hashAPIKey(key)}).One(&apiKey)
+	if err == mgo.ErrNotFound {
+		return nil, APIKeyNotFoundError
+	} else if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if now.Sub(apiKey.LastUsed) > apiKeyLastUsedInterval {
+		err = coll.UpdateId(apiKey.ID, bson.M{"$set": bson.M{"last_used": now}})
+		if err != nil {
+			return nil, err
+		}
+		apiKey.LastUsed = now
+	}
+	return &apiKey, nil
+}
+
+// DeleteAPIKeys revokes all the keys of the user
+func DeleteAPIKeys(uid bson.ObjectId) error {
+	sess := db.NewAPIKeyCollectionSession()
+	defer sess.Close()
+	_, err := sess.Collection.RemoveAll(bson.M{"uid": uid})
+	return err
+}
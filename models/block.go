@@ -0,0 +1,142 @@
+package models
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+)
+
+// BlockUser blocks the user with uid2 for the user with uid1. The follows
+// and the pending follow requests between them are removed in both
+// directions.
+func BlockUser(uid1 bson.ObjectId, uid2 bson.ObjectId) error {
+	if uid1 == uid2 {
+		return UserNotFoundError
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	n, err := coll.FindId(uid2).Count()
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return UserNotFoundError
+	}
+	err = coll.UpdateId(uid1, bson.M{
+		"$addToSet": bson.M{"blocked": uid2},
+		"$pull": bson.M{
+			"followingUsers": bson.M{"f_id": uid2},
+			"followRequests": bson.M{"f_id": uid2},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return coll.UpdateId(uid2, bson.M{
+		"$pull": bson.M{
+			"followingUsers": bson.M{"f_id": uid1},
+			"followRequests": bson.M{"f_id": uid1},
+		},
+	})
+}
+
+// UnblockUser removes the user with uid2 from the block list of the user
+// with uid1. The follows removed while blocking are not restored.
+func UnblockUser(uid1 bson.ObjectId, uid2 bson.ObjectId) error {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	return sess.Collection.UpdateId(uid1, bson.M{"$pull": bson.M{"blocked": uid2}})
+}
+
+// MuteUser hides the submissions of the user with uid2 from the feed of
+// the user with uid1, without affecting the follow
+func MuteUser(uid1 bson.ObjectId, uid2 bson.ObjectId) error {
+	if uid1 == uid2 {
+		return UserNotFoundError
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	n, err := coll.FindId(uid2).Count()
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return UserNotFoundError
+	}
+	return coll.UpdateId(uid1, bson.M{"$addToSet": bson.M{"muted": uid2}})
+}
+
+// UnmuteUser shows the submissions of the user with uid2 in the feed of
+// the user with uid1 again
+func UnmuteUser(uid1 bson.ObjectId, uid2 bson.ObjectId) error {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	return sess.Collection.UpdateId(uid1, bson.M{"$pull": bson.M{"muted": uid2}})
+}
+
+// GetBlockedUsers returns the users blocked by the user
+func GetBlockedUsers(uid bson.ObjectId) ([]types.FollowingUser, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	var user types.User
+	err := sess.Collection.FindId(uid).Select(bson.M{"blocked": 1}).One(&user)
+	if err != nil {
+		return nil, UserNotFoundError
+	}
+	return getUsersByID(sess.Collection, user.Blocked)
+}
+
+// GetMutedUsers returns the users muted by the user
+func GetMutedUsers(uid bson.ObjectId) ([]types.FollowingUser, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	var user types.User
+	err := sess.Collection.FindId(uid).Select(bson.M{"muted": 1}).One(&user)
+	if err != nil {
+		return nil, UserNotFoundError
+	}
+	return getUsersByID(sess.Collection, user.Muted)
+}
+
+func getUsersByID(coll *mgo.Collection, uids []bson.ObjectId) ([]types.FollowingUser, error) {
+	users := []types.FollowingUser{}
+	if len(uids) == 0 {
+		return users, nil
+	}
+	err := coll.Find(bson.M{"_id": bson.M{"$in": uids}}).Select(
+		bson.M{"_id": 1, "username": 1, "picture": 1,
+			"fullname": 1}).All(&users)
+	return users, err
+}
+
+// Returns the uids of the users blocked by the user and the users who
+// blocked the user, which are hidden from each other
+func getHiddenUIDs(uid bson.ObjectId) ([]bson.ObjectId, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	var user types.User
+	err := coll.FindId(uid).Select(bson.M{"blocked": 1}).One(&user)
+	if err != nil && err != mgo.ErrNotFound {
+		return nil, err
+	}
+	var blockedBy []bson.ObjectId
+	err = coll.Find(bson.M{"blocked": uid}).Distinct("_id", &blockedBy)
+	if err != nil {
+		return nil, err
+	}
+	return append(user.Blocked, blockedBy...), nil
+}
+
+// Reports whether either of the users blocked the other
+func isBlocked(uid1 bson.ObjectId, uid2 bson.ObjectId) (bool, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	n, err := sess.Collection.Find(bson.M{"$or": []bson.M{
+		{"_id": uid1, "blocked": uid2},
+		{"_id": uid2, "blocked": uid1},
+	}}).Count()
+	return n != 0, err
+}
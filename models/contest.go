@@ -8,11 +8,13 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
 	r "github.com/go-redis/redis"
 	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
 	"github.com/mdg-iitr/Codephile/services/redis"
 )
 
@@ -79,8 +81,14 @@ func fetchFromWeb() (types.Result, error) {
 
 	clistURL, _ := url.Parse("https://clist.by/api/v2/contest/")
 
+	var hosts []string
+	for _, site := range scrappers.Sites() {
+		if site.Host != "" {
+			hosts = append(hosts, regexp.QuoteMeta(site.Host))
+		}
+	}
 	values := clistURL.Query()
-	values.Set("host__regex", "codeforces.com|codechef.com|spoj.com|hackerrank.com|leetcode.com")
+	values.Set("host__regex", strings.Join(hosts, "|"))
 	values.Set("end__gte", time.Now().Format(time.RFC3339))
 	values.Set("order_by", "start")
 	values.Set("total_count", "true")
@@ -106,7 +114,7 @@ func fetchFromWeb() (types.Result, error) {
 	if err != nil {
 		return types.Result{}, err
 	}
-	result, err := clistResult.ToResult()
+	result, err := clistResult.ToResult(scrappers.SiteFromURL)
 	if err != nil {
 		return types.Result{}, err
 	}
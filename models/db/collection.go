@@ -32,6 +32,22 @@ func NewUserCollectionSession() *Collection {
 	return NewCollectionSession("coduser")
 }
 
+func NewSubmissionCollectionSession() *Collection {
+	return NewCollectionSession("submissions")
+}
+
+func NewParticipationCollectionSession() *Collection {
+	return NewCollectionSession("participations")
+}
+
+func NewAPIKeyCollectionSession() *Collection {
+	return NewCollectionSession("apikeys")
+}
+
+func NewAuditCollectionSession() *Collection {
+	return NewCollectionSession("audit")
+}
+
 func (c *Collection) Close() {
 	service.Close(c)
 }
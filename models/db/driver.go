@@ -4,6 +4,7 @@ import (
 	"github.com/astaxie/beego"
 	"github.com/getsentry/sentry-go"
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/mdg-iitr/Codephile/conf"
 	"log"
 	"os"
@@ -24,6 +25,95 @@ var emailIndex = mgo.Index{
 	Background: true,
 }
 
+// Reverse index of the follows, serves the followers of a user
+var followersIndex = mgo.Index{
+	Key:        []string{"followingUsers.f_id"},
+	Background: true,
+}
+
+// An account on an OAuth provider is linked to only one user
+var oauthIndexes = []mgo.Index{
+	{Key: []string{"oauth.google"}, Unique: true, Sparse: true, Background: true},
+	{Key: []string{"oauth.github"}, Unique: true, Sparse: true, Background: true},
+}
+
+// Reverse index of the blocks, serves the users who blocked a user
+var blockedIndex = mgo.Index{
+	Key:        []string{"blocked"},
+	Background: true,
+}
+
+// Serves the users of an institute
+var instituteIndex = mgo.Index{
+	Key:        []string{"institute"},
+	Background: true,
+}
+
+// Serves the users who solved a problem, for the recommendations
+var submissionURLIndex = mgo.Index{
+	Key:        []string{"url", "status"},
+	Background: true,
+}
+
+// Serves the submissions of a user and the feed, latest first. The id
+// orders the submissions created at the same time for the cursors.
+var submissionUserIndex = mgo.Index{
+	Key:        []string{"uid", "-created_at", "-_id"},
+	Background: true,
+}
+
+// Serves the per site solve counts, accuracy and filters
+var submissionSiteIndex = mgo.Index{
+	Key:        []string{"uid", "site", "status"},
+	Background: true,
+}
+
+// A submission is stored once. Submissions fetched before the site ids
+// were recorded have no id and are left out of the index.
+var submissionIDIndex = mgo.Index{
+	Key:           []string{"uid", "site", "id"},
+	Unique:        true,
+	PartialFilter: bson.M{"id": bson.M{"$exists": true}},
+	Background:    true,
+}
+
+// API keys are looked up by their hash
+var apiKeyHashIndex = mgo.Index{
+	Key:        []string{"hash"},
+	Unique:     true,
+	Background: true,
+}
+
+// Serves the API keys of a user
+var apiKeyUserIndex = mgo.Index{
+	Key:        []string{"uid"},
+	Background: true,
+}
+
+// Serves the audit log, latest first
+var auditTimeIndex = mgo.Index{
+	Key:        []string{"-created_at", "-_id"},
+	Background: true,
+}
+
+// Serves the audit log of the actions on a user
+var auditTargetIndex = mgo.Index{
+	Key:        []string{"target", "-created_at", "-_id"},
+	Background: true,
+}
+
+// A user takes part in a contest only once
+var participationIndex = mgo.Index{
+	Key:        []string{"uid", "site", "contest_id"},
+	Unique:     true,
+	Background: true,
+}
+
+var contestIndex = mgo.Index{
+	Key:        []string{"site", "contest_id"},
+	Background: true,
+}
+
 func init() {
 	var err error
 	maxPool, err = beego.AppConfig.Int("DBMaxPool")
@@ -46,12 +136,69 @@ func init() {
 		log.Println(err.Error())
 		sentry.CurrentHub().CaptureException(err)
 	}
+	err = c.Collection.EnsureIndex(followersIndex)
+	if err != nil {
+		log.Println(err.Error())
+		sentry.CurrentHub().CaptureException(err)
+	}
+	err = c.Collection.EnsureIndex(instituteIndex)
+	if err != nil {
+		log.Println(err.Error())
+		sentry.CurrentHub().CaptureException(err)
+	}
+	err = c.Collection.EnsureIndex(blockedIndex)
+	if err != nil {
+		log.Println(err.Error())
+		sentry.CurrentHub().CaptureException(err)
+	}
+	for _, index := range oauthIndexes {
+		err = c.Collection.EnsureIndex(index)
+		if err != nil {
+			log.Println(err.Error())
+			sentry.CurrentHub().CaptureException(err)
+		}
+	}
 	defer c.Close()
 	if err != nil {
 		sentry.CurrentHub().CaptureException(err)
 		log.Println(err.Error())
 	}
-
+	s := NewSubmissionCollectionSession()
+	defer s.Close()
+	for _, index := range []mgo.Index{submissionUserIndex, submissionSiteIndex, submissionIDIndex, submissionURLIndex} {
+		err = s.Collection.EnsureIndex(index)
+		if err != nil {
+			log.Println(err.Error())
+			sentry.CurrentHub().CaptureException(err)
+		}
+	}
+	p := NewParticipationCollectionSession()
+	defer p.Close()
+	for _, index := range []mgo.Index{participationIndex, contestIndex} {
+		err = p.Collection.EnsureIndex(index)
+		if err != nil {
+			log.Println(err.Error())
+			sentry.CurrentHub().CaptureException(err)
+		}
+	}
+	k := NewAPIKeyCollectionSession()
+	defer k.Close()
+	for _, index := range []mgo.Index{apiKeyHashIndex, apiKeyUserIndex} {
+		err = k.Collection.EnsureIndex(index)
+		if err != nil {
+			log.Println(err.Error())
+			sentry.CurrentHub().CaptureException(err)
+		}
+	}
+	a := NewAuditCollectionSession()
+	defer a.Close()
+	for _, index := range []mgo.Index{auditTimeIndex, auditTargetIndex} {
+		err = a.Collection.EnsureIndex(index)
+		if err != nil {
+			log.Println(err.Error())
+			sentry.CurrentHub().CaptureException(err)
+		}
+	}
 }
 
 func checkAndInitServiceConnection() {
@@ -3,7 +3,6 @@ package models
 import (
 	"github.com/mdg-iitr/Codephile/models/db"
 	"github.com/mdg-iitr/Codephile/models/types"
-	"time"
 
 	"github.com/globalsign/mgo/bson"
 )
@@ -13,12 +12,13 @@ func GetContestsFeed() (types.Result, error) {
 	return contestsFromCache()
 }
 
-func GetAllFeed(uid bson.ObjectId) ([]types.FeedObject, error) {
+// Returns the uids of the users followed by the user. Private users are
+// left out, as their activity is hidden from the followers too.
+func getFollowingUIDs(uid bson.ObjectId) ([]bson.ObjectId, error) {
 	sess := db.NewUserCollectionSession()
 	defer sess.Close()
-	coll := sess.Collection
 	var u types.User
-	err := coll.FindId(uid).Select(bson.M{"followingUsers.f_id": 1}).One(&u)
+	err := sess.Collection.FindId(uid).Select(bson.M{"followingUsers.f_id": 1}).One(&u)
 	if err != nil {
 		return nil, err
 	}
@@ -26,95 +26,142 @@ func GetAllFeed(uid bson.ObjectId) ([]types.FeedObject, error) {
 	for _, f := range u.FollowingUsers {
 		followingUID = append(followingUID, f.ID)
 	}
+	visible := []bson.ObjectId{}
+	err = sess.Collection.Find(bson.M{
+		"_id":     bson.M{"$in": followingUID},
+		"privacy": bson.M{"$ne": types.PrivacyPrivate},
+	}).Distinct("_id", &visible)
+	return visible, err
+}
+
+// Returns the uids of the users whose submissions make up the feed of the
+// user, i.e. the followed users which are not muted
+func getFeedUIDs(uid bson.ObjectId) ([]bson.ObjectId, error) {
+	followingUID, err := getFollowingUIDs(uid)
+	if err != nil {
+		return nil, err
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	var u types.User
+	err = sess.Collection.FindId(uid).Select(bson.M{"muted": 1}).One(&u)
+	if err != nil {
+		return nil, err
+	}
+	isMuted := make(map[bson.ObjectId]bool, len(u.Muted))
+	for _, id := range u.Muted {
+		isMuted[id] = true
+	}
+	feedUID := followingUID[:0]
+	for _, id := range followingUID {
+		if !isMuted[id] {
+			feedUID = append(feedUID, id)
+		}
+	}
+	return feedUID, nil
+}
+
+// Returns the pipeline stages attaching the details of the submitter to
+// every submission, in the shape of a FeedObject
+func feedLookupStages() []bson.M {
+	return []bson.M{
+		{
+			"$project": bson.M{
+				"_id":        0,
+				"uid":        1,
+				"submission": "$$ROOT",
+			},
+		},
+		{
+			"$lookup": bson.M{
+				"from":         "coduser",
+				"localField":   "uid",
+				"foreignField": "_id",
+				"as":           "user",
+			},
+		},
+		{
+			"$unwind": "$user",
+		},
+		{
+			"$project": bson.M{
+				"_id":        "$user._id",
+				"username":   "$user.username",
+				"picture":    "$user.picture",
+				"fullname":   "$user.fullname",
+				"submission": 1,
+			},
+		},
+	}
+}
+
+func GetAllFeed(uid bson.ObjectId) ([]types.FeedObject, error) {
+	followingUID, err := getFeedUIDs(uid)
+	if err != nil {
+		return nil, err
+	}
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
 	filter := bson.M{
 		"$match": bson.M{
-			"_id": bson.M{
+			"uid": bson.M{
 				"$in": followingUID,
 			},
 		},
 	}
-	project := bson.M{
-		"$project": bson.M{
-			"_id":        1,
-			"username":   1,
-			"picture":    1,
-			"fullname":   1,
-			"submission": "$submissions",
-		},
-	}
-	unwind := bson.M{
-		"$unwind": "$submission",
-	}
 	sort := bson.M{
 		"$sort": bson.M{
-			"submission.created_at": -1,
+			"created_at": -1,
 		},
 	}
-	pipe := coll.Pipe([]bson.M{
+	pipe := coll.Pipe(append([]bson.M{
 		filter,
-		project,
-		unwind,
 		sort,
-	}, )
+	}, feedLookupStages()...))
 	var res []types.FeedObject
 	err = pipe.All(&res)
 	return res, err
 }
 
-func GetFeed(uid bson.ObjectId, before time.Time) ([]types.FeedObject, error) {
-	sess := db.NewUserCollectionSession()
-	defer sess.Close()
-	coll := sess.Collection
-	var u types.User
-	err := coll.FindId(uid).Select(bson.M{"followingUsers.f_id": 1}).One(&u)
+// GetFeed returns a page of at most limit submissions of the users
+// followed by the user after the cursor, latest first
+func GetFeed(uid bson.ObjectId, cursor types.Cursor, limit int) (types.FeedPage, error) {
+	followingUID, err := getFeedUIDs(uid)
 	if err != nil {
-		return nil, err
+		return types.FeedPage{}, err
 	}
-	followingUID := make([]bson.ObjectId, 0, len(u.FollowingUsers))
-	for _, f := range u.FollowingUsers {
-		followingUID = append(followingUID, f.ID)
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	match := cursor.Filter()
+	match["uid"] = bson.M{
+		"$in": followingUID,
 	}
 	filter := bson.M{
-		"$match": bson.M{
-			"_id": bson.M{
-				"$in": followingUID,
-			},
-		},
-	}
-	project := bson.M{
-		"$project": bson.M{
-			"_id":      1,
-			"username": 1,
-			"picture":  1,
-			"fullname": 1,
-			"submission": bson.M{"$filter": bson.M{"input": "$submissions",
-				"as":   "sub",
-				"cond": bson.M{"$lt": []interface{}{"$$sub.created_at", before}},
-			}},
-		},
-	}
-	unwind := bson.M{
-		"$unwind": "$submission",
+		"$match": match,
 	}
 	sort := bson.M{
-		"$sort": bson.M{
-			"submission.created_at": -1,
+		"$sort": bson.D{
+			{Name: "created_at", Value: -1},
+			{Name: "_id", Value: -1},
 		},
 	}
-	limit := bson.M{
-		"$limit": 100,
-	}
-
-	pipe := coll.Pipe([]bson.M{
+	pipe := coll.Pipe(append([]bson.M{
 		filter,
-		project,
-		unwind,
 		sort,
-		limit,
-	}, )
+		{"$limit": limit},
+	}, feedLookupStages()...))
 
-	var res []types.FeedObject
+	res := []types.FeedObject{}
 	err = pipe.All(&res)
-	//fmt.Println(res)
-	return res, err
+	if err != nil {
+		return types.FeedPage{}, err
+	}
+	page := types.FeedPage{Data: res}
+	if len(res) == limit {
+		last := res[len(res)-1].Submission
+		page.NextCursor = types.Cursor{Time: last.CreationDate, ID: last.DocumentID}.String()
+	}
+	return page, nil
 }
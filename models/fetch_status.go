@@ -0,0 +1,76 @@
+package models
+
+import (
+	"log"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+)
+
+// RecordFetchResult stores the result of an attempt to fetch the data of
+// the user from the site. retrying tells if a failed fetch will be
+// attempted again.
+func RecordFetchResult(uid bson.ObjectId, site string, err error, retrying bool) {
+	if !scrappers.IsSiteValid(site) {
+		return
+	}
+	node := "fetch_status." + site + "."
+	now := time.Now().UTC()
+	update := bson.M{}
+	if err == nil {
+		update["$set"] = bson.M{
+			node + "last_attempt": now,
+			node + "last_success": now,
+			node + "result":       types.FetchSucceeded,
+		}
+		update["$unset"] = bson.M{node + "error_kind": "", node + "error": ""}
+	} else {
+		result := types.FetchFailed
+		if retrying {
+			result = types.FetchRetrying
+		}
+		kind := types.FetchErrorInternal
+		if k, ok := ScrapeErrorKindOf(err); ok {
+			kind = string(k)
+		}
+		update["$set"] = bson.M{
+			node + "last_attempt": now,
+			node + "result":       result,
+			node + "error_kind":   kind,
+			node + "error":        err.Error(),
+		}
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	if err := sess.Collection.UpdateId(uid, update); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// GetFetchStatus returns the result of the latest fetch of every site
+// for which the user has set a handle
+func GetFetchStatus(uid bson.ObjectId) (types.FetchStatuses, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	var user types.User
+	err := sess.Collection.FindId(uid).Select(bson.M{"handle": 1, "fetch_status": 1}).One(&user)
+	if err != nil {
+		return nil, err
+	}
+	statuses := types.FetchStatuses{}
+	for _, site := range scrappers.Sites() {
+		if user.Handle.Get(site.Name) == "" {
+			continue
+		}
+		status, ok := user.FetchStatus[site.Name]
+		if !ok {
+			status.Result = types.FetchNotAttempted
+		}
+		statuses[site.Name] = status
+	}
+	return statuses, nil
+}
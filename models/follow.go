@@ -1,6 +1,9 @@
 package models
 
 import (
+	"time"
+
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models/db"
@@ -28,9 +31,81 @@ func GetFollowingUsers(ID bson.ObjectId) ([]types.FollowingUser, error) {
 	if err2 != nil {
 		return nil, err2
 	}
+	// Following users who follow back
+	var mutual []bson.ObjectId
+	err = coll.Find(bson.M{"_id": bson.M{"$in": followingUIDs}, "followingUsers.f_id": ID}).
+		Distinct("_id", &mutual)
+	if err != nil {
+		return nil, err
+	}
+	markMutual(followingUsers, mutual)
 	return followingUsers, nil
 }
 
+// GetFollowers returns the users following the user
+func GetFollowers(ID bson.ObjectId) ([]types.FollowingUser, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	var user types.User
+	err := coll.FindId(ID).Select(bson.M{"followingUsers.f_id": 1}).One(&user)
+	if err != nil {
+		return nil, UserNotFoundError
+	}
+	followers := []types.FollowingUser{}
+	err = coll.Find(bson.M{"followingUsers.f_id": ID}).Select(
+		bson.M{"_id": 1, "username": 1, "picture": 1,
+			"fullname": 1}).All(&followers)
+	if err != nil {
+		return nil, err
+	}
+	// Followers whom the user follows back
+	mutual := make([]bson.ObjectId, 0, len(user.FollowingUsers))
+	for _, f := range user.FollowingUsers {
+		mutual = append(mutual, f.ID)
+	}
+	markMutual(followers, mutual)
+	return followers, nil
+}
+
+func markMutual(users []types.FollowingUser, mutual []bson.ObjectId) {
+	isMutual := make(map[bson.ObjectId]bool, len(mutual))
+	for _, uid := range mutual {
+		isMutual[uid] = true
+	}
+	for i := range users {
+		users[i].Mutual = isMutual[users[i].ID]
+	}
+}
+
+// Returns the number of followers of each of the users
+func getFollowerCounts(uids []bson.ObjectId) (map[bson.ObjectId]int, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	match := bson.M{"$match": bson.M{"followingUsers.f_id": bson.M{"$in": uids}}}
+	pipe := sess.Collection.Pipe([]bson.M{
+		match,
+		{"$unwind": "$followingUsers"},
+		// Leaves out the other users followed by the followers
+		match,
+		{"$group": bson.M{"_id": "$followingUsers.f_id", "count": bson.M{"$sum": 1}}},
+	})
+	var res []struct {
+		ID    bson.ObjectId `bson:"_id"`
+		Count int           `bson:"count"`
+	}
+	if err := pipe.All(&res); err != nil {
+		return nil, err
+	}
+	counts := make(map[bson.ObjectId]int, len(res))
+	for _, r := range res {
+		counts[r.ID] = r.Count
+	}
+	return counts, nil
+}
+
+// UnFollowUser un-follows the user, or withdraws the follow request if
+// it is not approved yet
 func UnFollowUser(uid1 bson.ObjectId, uid2 bson.ObjectId) error {
 	sess := db.NewUserCollectionSession()
 	defer sess.Close()
@@ -42,22 +117,165 @@ func UnFollowUser(uid1 bson.ObjectId, uid2 bson.ObjectId) error {
 			},
 		},
 	}
-	return coll.UpdateId(uid1, update)
+	err := coll.UpdateId(uid1, update)
+	if err != nil {
+		return err
+	}
+	err = coll.UpdateId(uid2, bson.M{"$pull": bson.M{"followRequests": bson.M{"f_id": uid1}}})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
 }
 
-func FollowUser(uid1 bson.ObjectId, uid2 bson.ObjectId) error {
+// FollowUser follows the user if the profile is public, otherwise sends
+// a follow request to be approved by the user. Returns whether the
+// request was sent, or UserBlockedError if either user blocked the other.
+func FollowUser(uid1 bson.ObjectId, uid2 bson.ObjectId) (bool, error) {
 	//uid1 is of the person who wants to follow
 	//uid2 is the person being followed
 	user1, err1 := GetUser(uid1)
 	user2, err2 := GetUser(uid2)
 	if err1 != nil || err2 != nil {
-		return UserNotFoundError
+		return false, UserNotFoundError
+	}
+	blocked, err := isBlocked(user1.ID, user2.ID)
+	if err != nil {
+		return false, err
+	} else if blocked {
+		return false, UserBlockedError
+	}
+	collection := db.NewUserCollectionSession()
+	defer collection.Close()
+	if user2.Privacy == types.PrivacyFollowers || user2.Privacy == types.PrivacyPrivate {
+		following, err := isFollowing(user1.ID, user2.ID)
+		if err != nil || following {
+			return false, err
+		}
+		request := types.FollowRequest{ID: user1.ID, Time: time.Now().UTC()}
+		// Requests already sent are left as they are
+		err = collection.Collection.Update(
+			bson.M{"_id": user2.ID, "followRequests.f_id": bson.M{"$ne": user1.ID}},
+			bson.M{"$push": bson.M{"followRequests": request}},
+		)
+		if err != nil && err != mgo.ErrNotFound {
+			return false, err
+		}
+		return true, nil
 	}
 	//add the uid2 in the database of uid1
 	var following types.Following
 	following.ID = user2.ID
 	update := bson.M{"$addToSet": bson.M{"followingUsers": following}}
-	collection := db.NewUserCollectionSession()
-	defer collection.Close()
-	return collection.Collection.UpdateId(user1.ID, update)
+	return false, collection.Collection.UpdateId(user1.ID, update)
+}
+
+// Reports whether uid1 follows uid2
+func isFollowing(uid1 bson.ObjectId, uid2 bson.ObjectId) (bool, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	n, err := sess.Collection.Find(bson.M{"_id": uid1, "followingUsers.f_id": uid2}).Count()
+	return n != 0, err
+}
+
+// GetFollowRequests returns the users waiting for the approval of the
+// user to follow them, earliest first
+func GetFollowRequests(uid bson.ObjectId) ([]types.FollowingUser, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	var user types.User
+	err := coll.FindId(uid).Select(bson.M{"followRequests": 1}).One(&user)
+	if err != nil {
+		return nil, UserNotFoundError
+	}
+	requesters := make([]bson.ObjectId, 0, len(user.FollowRequests))
+	for _, r := range user.FollowRequests {
+		requesters = append(requesters, r.ID)
+	}
+	var users []types.FollowingUser
+	err = coll.Find(bson.M{"_id": bson.M{"$in": requesters}}).Select(
+		bson.M{"_id": 1, "username": 1, "picture": 1,
+			"fullname": 1}).All(&users)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[bson.ObjectId]types.FollowingUser, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	requests := make([]types.FollowingUser, 0, len(users))
+	for _, id := range requesters {
+		// Requesters might have been deleted
+		if u, ok := byID[id]; ok {
+			requests = append(requests, u)
+		}
+	}
+	return requests, nil
+}
+
+// AcceptFollowRequest makes the requester follow the user.
+// Returns FollowRequestNotFoundError if the requester has no pending request
+func AcceptFollowRequest(uid bson.ObjectId, requester bson.ObjectId) error {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	err := coll.Update(
+		bson.M{"_id": uid, "followRequests.f_id": requester},
+		bson.M{"$pull": bson.M{"followRequests": bson.M{"f_id": requester}}},
+	)
+	if err == mgo.ErrNotFound {
+		return FollowRequestNotFoundError
+	} else if err != nil {
+		return err
+	}
+	err = coll.UpdateId(requester, bson.M{"$addToSet": bson.M{"followingUsers": types.Following{ID: uid}}})
+	if err == mgo.ErrNotFound {
+		return FollowRequestNotFoundError
+	}
+	return err
+}
+
+// RejectFollowRequest removes the pending request of the requester.
+// Returns FollowRequestNotFoundError if the requester has no pending request
+func RejectFollowRequest(uid bson.ObjectId, requester bson.ObjectId) error {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	err := sess.Collection.Update(
+		bson.M{"_id": uid, "followRequests.f_id": requester},
+		bson.M{"$pull": bson.M{"followRequests": bson.M{"f_id": requester}}},
+	)
+	if err == mgo.ErrNotFound {
+		return FollowRequestNotFoundError
+	}
+	return err
+}
+
+// CanView reports whether the viewer is allowed to see the profile and
+// the activity of the user. Users who blocked each other can't.
+// Returns UserNotFoundError if the user doesn't exist
+func CanView(viewer bson.ObjectId, uid bson.ObjectId) (bool, error) {
+	if viewer == uid {
+		return true, nil
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	var user types.User
+	err := sess.Collection.FindId(uid).Select(bson.M{"privacy": 1}).One(&user)
+	if err == mgo.ErrNotFound {
+		return false, UserNotFoundError
+	} else if err != nil {
+		return false, err
+	}
+	if blocked, err := isBlocked(viewer, uid); err != nil || blocked {
+		return false, err
+	}
+	switch user.Privacy {
+	case types.PrivacyPrivate:
+		return false, nil
+	case types.PrivacyFollowers:
+		return isFollowing(viewer, uid)
+	default:
+		return true, nil
+	}
 }
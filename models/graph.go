@@ -8,28 +8,23 @@ import (
 )
 
 func GetActivityGraph(uid bson.ObjectId) (types.ActivityGraph, error) {
-	sess := db.NewUserCollectionSession()
+	sess := db.NewSubmissionCollectionSession()
 	defer sess.Close()
 	coll := sess.Collection
 
-	match := bson.M{"$match": bson.M{"_id": uid}}
-	project := bson.M{"$project": bson.M{"submission": "$submissions"}}
-	unwind := bson.M{"$unwind": "$submission"}
+	match := bson.M{"$match": bson.M{"uid": uid}}
 	group := bson.M{
 		"$group": bson.M{
-			"_id": bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$submission.created_at"}},
-			//date: {$min: "$submission.created_at"},
+			"_id": bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$created_at"}},
 			"correct": bson.M{
 				"$sum": bson.M{
-					"$cond": []interface{}{bson.M{"$eq": []string{"$submission.status", conf.StatusCorrect}}, 1, 0},
+					"$cond": []interface{}{bson.M{"$eq": []string{"$status", conf.StatusCorrect}}, 1, 0},
 				},
 			},
 			"total": bson.M{"$sum": 1},
 		}}
 	pipe := coll.Pipe([]bson.M{
 		match,
-		project,
-		unwind,
 		group,
 	})
 	var res types.ActivityGraph
@@ -38,39 +33,57 @@ func GetActivityGraph(uid bson.ObjectId) (types.ActivityGraph, error) {
 }
 
 func GetStatusCounts(uid bson.ObjectId) (types.StatusCounts, error) {
-	sess := db.NewUserCollectionSession()
+	sess := db.NewSubmissionCollectionSession()
 	defer sess.Close()
 	coll := sess.Collection
 
-	match := bson.M{"$match": bson.M{"_id": uid}}
-	project := bson.M{
-		"$project": bson.M{
-			"ac_count":  GetStatusQuery(conf.StatusCorrect),
-			"wa_count":  GetStatusQuery(conf.StatusWrongAnswer),
-			"ce_count":  GetStatusQuery(conf.StatusCompilationError),
-			"re_count":  GetStatusQuery(conf.StatusRuntimeError),
-			"tle_count": GetStatusQuery(conf.StatusTimeLimitExceeded),
-			"mle_count": GetStatusQuery(conf.StatusMemoryLimitExceeded),
-			"ptl_count": GetStatusQuery(conf.StatusPartial),
-		}}
+	match := bson.M{"$match": bson.M{"uid": uid}}
+	group := bson.M{"$group": bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}
 	pipe := coll.Pipe([]bson.M{
 		match,
-		project,
+		group,
 	})
 
+	var res []struct {
+		Status string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}
 	var statusCounts types.StatusCounts
-	err := pipe.One(&statusCounts)
-	return statusCounts, err
+	err := pipe.All(&res)
+	if err != nil {
+		return statusCounts, err
+	}
+	for _, r := range res {
+		switch r.Status {
+		case conf.StatusCorrect:
+			statusCounts.StatusCorrect = r.Count
+		case conf.StatusWrongAnswer:
+			statusCounts.StatusWrongAnswer = r.Count
+		case conf.StatusCompilationError:
+			statusCounts.StatusCompilationError = r.Count
+		case conf.StatusRuntimeError:
+			statusCounts.StatusRuntimeError = r.Count
+		case conf.StatusTimeLimitExceeded:
+			statusCounts.StatusTimeLimitExceeded = r.Count
+		case conf.StatusMemoryLimitExceeded:
+			statusCounts.StatusMemoryLimitExceeded = r.Count
+		case conf.StatusPartial:
+			statusCounts.StatusPartial = r.Count
+		}
+	}
+	return statusCounts, nil
 }
 
-func GetStatusQuery(status string) bson.M {
-	return bson.M{
-		"$size": bson.M{
-			"$filter": bson.M{
-				"input": "$submissions",
-				"as":    "sub",
-				"cond":  bson.M{"$eq": []string{"$$sub.status", status}},
-			},
-		},
+// GetRatingHistory returns the contest rating history of the user on the site
+func GetRatingHistory(uid bson.ObjectId, site string) (types.RatingHistory, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+
+	var user types.User
+	err := coll.FindId(uid).Select(bson.M{"ratings." + site: 1}).One(&user)
+	if err != nil {
+		return nil, err
 	}
+	return user.Ratings[site], nil
 }
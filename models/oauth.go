@@ -0,0 +1,137 @@
+package models
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/services/oauth"
+)
+
+// Attempts at picking a free username for a user signing up via a provider
+const maxUsernameAttempts = 5
+
+func oauthField(provider string) string {
+	return "oauth." + provider
+}
+
+// LoginWithOAuth returns the user linked to the identity on the provider.
+// If no user is linked yet, the identity is linked to the user with the
+// same email when both the provider and the user verified it, otherwise a
+// new user is created, verified if the provider verified the email.
+// An unverified user with the same email can't be taken over this way,
+// UserAlreadyExistError is returned instead.
+func LoginWithOAuth(provider string, identity oauth.Identity) (*types.User, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	var user types.User
+	err := coll.Find(bson.M{oauthField(provider): identity.ID}).Select(bson.M{"_id": 1}).One(&user)
+	if err == nil {
+		return &user, nil
+	} else if err != mgo.ErrNotFound {
+		return nil, err
+	}
+
+	if identity.Email != "" && identity.EmailVerified {
+		change := mgo.Change{
+			Update: bson.M{"$set": bson.M{oauthField(provider): identity.ID}},
+		}
+		selector := bson.M{"email": identity.Email, "verified": true, oauthField(provider): bson.M{"$exists": false}}
+		_, err = coll.Find(selector).Select(bson.M{"_id": 1}).Apply(change, &user)
+		if err == nil {
+			return &user, nil
+		} else if err != mgo.ErrNotFound {
+			return nil, err
+		}
+	}
+
+	if identity.Email == "" {
+		// The unique email index doesn't allow users without email
+		return nil, FieldEmptyError
+	}
+	return addOAuthUser(provider, identity)
+}
+
+// Creates a user without password, who can log in only via the provider
+func addOAuthUser(provider string, identity oauth.Identity) (*types.User, error) {
+	base := identity.Login
+	if base == "" {
+		base = strings.Split(identity.Email, "@")[0]
+	}
+	username := base
+	for i := 0; i < maxUsernameAttempts; i++ {
+		exists, err := CheckUsernameExists(username)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			break
+		}
+		username = base + strconv.Itoa(1000+rand.Intn(9000))
+	}
+	id, err := AddUser(types.User{
+		Username: username,
+		Email:    identity.Email,
+		FullName: identity.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	uid := bson.ObjectIdHex(id)
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	update := bson.M{
+		oauthField(provider): identity.ID,
+		"verified":           identity.EmailVerified,
+		// The hash of the empty password set by AddUser would let anyone in
+		"password": "",
+	}
+	if identity.Picture != "" {
+		update["picture"] = identity.Picture
+	}
+	err = sess.Collection.UpdateId(uid, bson.M{"$set": update})
+	if err != nil {
+		return nil, err
+	}
+	return &types.User{ID: uid, Username: username}, nil
+}
+
+// LinkOAuth links the identity on the provider to the user, replacing the
+// identity linked earlier.
+// Returns OAuthAlreadyLinkedError if the identity is linked to another user
+func LinkOAuth(uid bson.ObjectId, provider string, identity oauth.Identity) error {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	err := sess.Collection.UpdateId(uid, bson.M{"$set": bson.M{oauthField(provider): identity.ID}})
+	if mgo.IsDup(err) {
+		return OAuthAlreadyLinkedError
+	}
+	return err
+}
+
+// UnlinkOAuth removes the identity on the provider from the user.
+// Returns LastLoginMethodError if the user has neither a password nor
+// another provider to log in with
+func UnlinkOAuth(uid bson.ObjectId, provider string) error {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	var user types.User
+	err := coll.FindId(uid).Select(bson.M{"password": 1, "oauth": 1}).One(&user)
+	if err != nil {
+		return UserNotFoundError
+	}
+	if _, ok := user.OAuth[provider]; !ok {
+		return nil
+	}
+	if user.Password == "" && len(user.OAuth) == 1 {
+		return LastLoginMethodError
+	}
+	return coll.UpdateId(uid, bson.M{"$unset": bson.M{oauthField(provider): ""}})
+}
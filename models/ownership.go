@@ -0,0 +1,134 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/globalsign/mgo/bson"
+	r "github.com/go-redis/redis"
+	"github.com/google/uuid"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/services/redis"
+)
+
+// Time for which an ownership token can be confirmed
+var ownershipTokenTTL = time.Duration(beego.AppConfig.DefaultInt("OWNERSHIP_TOKEN_TTL", 3600)) * time.Second
+
+func ownershipKey(uid bson.ObjectId, site string) string {
+	return "ownership:" + uid.Hex() + ":" + site
+}
+
+// Picks one of the choices at random, empty if there are none
+func pickRandom(choices []string) (string, error) {
+	if len(choices) == 0 {
+		return "", nil
+	}
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(choices))))
+	if err != nil {
+		return "", err
+	}
+	return choices[i.Int64()], nil
+}
+
+// IssueOwnershipToken creates the token to be placed by the user on the
+// site to prove the ownership of the handle. Token issued earlier for the
+// site is replaced.
+// Returns OwnershipUnsupportedError if the site can't verify the ownership
+func IssueOwnershipToken(uid bson.ObjectId, site string, handle string, ctx context.Context) (types.OwnershipChallenge, error) {
+	scrapper, err := scrappers.NewScrapper(site, handle, ctx)
+	if err != nil {
+		return types.OwnershipChallenge{}, err
+	}
+	verifier, ok := scrapper.(scrappers.OwnershipScrapper)
+	if !ok {
+		return types.OwnershipChallenge{}, OwnershipUnsupportedError
+	}
+	// Picked for every challenge so that a compilation error submitted
+	// before can't be passed off as the proof
+	problem, err := pickRandom(verifier.OwnershipProblems())
+	if err != nil {
+		return types.OwnershipChallenge{}, err
+	}
+	language, err := pickRandom(verifier.OwnershipLanguages())
+	if err != nil {
+		return types.OwnershipChallenge{}, err
+	}
+	now := time.Now().UTC()
+	challenge := types.OwnershipChallenge{
+		Site:   site,
+		Handle: handle,
+		// Short enough to fit in the name fields of the sites
+		Token:     "codephile-" + strings.Replace(uuid.New().String(), "-", "", -1)[:10],
+		Problem:   problem,
+		Language:  language,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ownershipTokenTTL),
+	}
+	raw, err := json.Marshal(challenge)
+	if err != nil {
+		return types.OwnershipChallenge{}, err
+	}
+	err = redis.GetRedisClient().Set(ownershipKey(uid, site), raw, ownershipTokenTTL).Err()
+	if err != nil {
+		return types.OwnershipChallenge{}, err
+	}
+	return challenge, nil
+}
+
+// ConfirmOwnership checks whether the user placed the token issued for
+// the site. Once confirmed, the handle is linked to the user and marked
+// verified.
+// Returns OwnershipTokenNotFoundError if no token is pending for the site
+func ConfirmOwnership(uid bson.ObjectId, site string, ctx context.Context) (bool, error) {
+	client := redis.GetRedisClient()
+	raw, err := client.Get(ownershipKey(uid, site)).Bytes()
+	if err == r.Nil {
+		return false, OwnershipTokenNotFoundError
+	} else if err != nil {
+		return false, err
+	}
+	var challenge types.OwnershipChallenge
+	if err = json.Unmarshal(raw, &challenge); err != nil {
+		return false, err
+	}
+	scrapper, err := scrappers.NewScrapper(site, challenge.Handle, ctx)
+	if err != nil {
+		return false, err
+	}
+	verifier, ok := scrapper.(scrappers.OwnershipScrapper)
+	if !ok {
+		return false, OwnershipUnsupportedError
+	}
+	owned, err := verifier.CheckOwnership(challenge)
+	if err != nil || !owned {
+		return false, err
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	// The latest proof wins, the handle may have changed hands
+	_, err = sess.Collection.UpdateAll(bson.M{"_id": bson.M{"$ne": uid}, "verified_handles." + site: challenge.Handle},
+		bson.M{"$unset": bson.M{"verified_handles." + site: ""}})
+	if err != nil {
+		return false, err
+	}
+	// Links the handle, fetching its data if it was changed
+	var user types.User
+	user.Handle.Set(site, challenge.Handle)
+	if _, err = UpdateUser(uid, &user, ctx); err != nil {
+		return false, err
+	}
+	err = sess.Collection.UpdateId(uid, bson.M{"$set": bson.M{"verified_handles." + site: challenge.Handle}})
+	if err != nil {
+		return false, err
+	}
+	client.Del(ownershipKey(uid, site))
+	return true, nil
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+)
+
+// Stores the contests of a user on a site, updating the already stored ones
+func updateParticipations(uid bson.ObjectId, site string, contests []types.ContestParticipation) error {
+	if len(contests) == 0 {
+		return nil
+	}
+	sess := db.NewParticipationCollectionSession()
+	defer sess.Close()
+	bulk := sess.Collection.Bulk()
+	bulk.Unordered()
+	for _, contest := range contests {
+		contest.UserID = uid
+		contest.Site = site
+		// Set rather than replaced, so that a solve count left out by the
+		// scrapper keeps the stored one
+		bulk.Upsert(bson.M{"uid": uid, "site": site, "contest_id": contest.ContestID}, bson.M{"$set": contest})
+	}
+	_, err := bulk.Run()
+	return err
+}
+
+// DeleteParticipations removes the stored contests of the user on a site,
+// or on every site if site is empty
+func DeleteParticipations(uid bson.ObjectId, site string) error {
+	sess := db.NewParticipationCollectionSession()
+	defer sess.Close()
+	selector := bson.M{"uid": uid}
+	if site != "" {
+		selector["site"] = site
+	}
+	_, err := sess.Collection.RemoveAll(selector)
+	return err
+}
+
+// GetParticipations returns the contests of the user, latest first
+func GetParticipations(uid bson.ObjectId) ([]types.ContestParticipation, error) {
+	sess := db.NewParticipationCollectionSession()
+	defer sess.Close()
+	contests := []types.ContestParticipation{}
+	err := sess.Collection.Find(bson.M{"uid": uid}).Sort("-time").All(&contests)
+	return contests, err
+}
+
+// GetFollowingParticipations returns the performance of the user and the
+// users followed by them in a contest, ordered by rank
+func GetFollowingParticipations(uid bson.ObjectId, site string, contestID string) ([]types.FollowingParticipation, error) {
+	following, err := getFollowingUIDs(uid)
+	if err != nil {
+		return nil, UserNotFoundError
+	}
+	uids := append([]bson.ObjectId{uid}, following...)
+
+	pSess := db.NewParticipationCollectionSession()
+	defer pSess.Close()
+	match := bson.M{"$match": bson.M{
+		"site":       site,
+		"contest_id": contestID,
+		"uid":        bson.M{"$in": uids},
+	}}
+	lookup := bson.M{"$lookup": bson.M{
+		"from":         "coduser",
+		"localField":   "uid",
+		"foreignField": "_id",
+		"as":           "user",
+	}}
+	unwind := bson.M{"$unwind": "$user"}
+	project := bson.M{"$project": bson.M{
+		"uid": 1, "site": 1, "contest_id": 1, "contest_name": 1, "rank": 1,
+		"rating": 1, "rating_change": 1, "problems_solved": 1, "time": 1,
+		"user._id": 1, "user.username": 1, "user.fullname": 1, "user.picture": 1,
+	}}
+	sort := bson.M{"$sort": bson.M{"rank": 1}}
+	pipe := pSess.Collection.Pipe([]bson.M{match, lookup, unwind, project, sort})
+	participations := []types.FollowingParticipation{}
+	err = pipe.All(&participations)
+	return participations, err
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+
 	"github.com/globalsign/mgo/bson"
 	. "github.com/mdg-iitr/Codephile/conf"
 	. "github.com/mdg-iitr/Codephile/errors"
@@ -13,12 +15,19 @@ import (
 )
 
 func ResetProfile(uid bson.ObjectId, site string) error {
+	err := DeleteParticipations(uid, site)
+	if err != nil {
+		return err
+	}
 	sess := db.NewUserCollectionSession()
 	defer sess.Close()
 	coll := sess.Collection
 	newNode := "profiles." + site + "Profile"
 	userProfile := types.ProfileInfo{}
-	return coll.UpdateId(uid, bson.M{"$set": bson.M{newNode: userProfile}})
+	return coll.UpdateId(uid, bson.M{
+		"$set":   bson.M{newNode: userProfile},
+		"$unset": bson.M{"ratings." + site: "", "fetch_status." + site: ""},
+	})
 }
 
 func AddOrUpdateProfile(uid bson.ObjectId, site string, ctx context.Context) error {
@@ -31,24 +40,60 @@ func AddOrUpdateProfile(uid bson.ObjectId, site string, ctx context.Context) err
 		//handle the error (Invalid user)
 		return UserNotFoundError
 	}
-	handle := result["handle"].(map[string]interface{})[site].(string)
+	handle, _ := result["handle"].(map[string]interface{})[site].(string)
 	var userProfile types.ProfileInfo
 	//runs code to fetch the particular script's getProfile function
 	scrapper, err := scrappers.NewScrapper(site, handle, ctx)
 	if err != nil {
 		return err
 	}
-	userProfile = scrapper.GetProfileInfo()
-	accuracy, err := GetAccuracy(uid, site)
+	userProfile, err = scrapper.GetProfileInfo()
 	if err != nil {
-		userProfile.Accuracy = ""
-	} else {
-		userProfile.Accuracy = accuracy
+		// Previous profile is kept rather than replaced by an empty one
+		return err
+	}
+	// Sites not reporting verdicts keep the accuracy returned by scrapper
+	if s, _ := scrappers.Lookup(site); s.Has(scrappers.Verdicts) {
+		accuracy, err := GetAccuracy(uid, site)
+		if err != nil {
+			userProfile.Accuracy = ""
+		} else {
+			userProfile.Accuracy = accuracy
+		}
 	}
 
 	//Profile fetched. Store in database
 	newNode := "profiles." + site + "Profile"
-	return coll.UpdateId(uid, bson.M{"$set": bson.M{newNode: userProfile}})
+	update := bson.M{newNode: userProfile}
+	// Failure to fetch rating history or contests keeps their previously
+	// stored data, but doesn't fail the fetch. It is recorded in the fetch
+	// status instead, as retrying would fetch the profile again.
+	var scrapeErr error
+	if rs, ok := scrapper.(scrappers.RatingScrapper); ok {
+		history, err := rs.GetRatingHistory()
+		if err != nil {
+			scrapeErr = err
+		} else if len(history) != 0 {
+			update["ratings."+site] = history
+		}
+	}
+	if cs, ok := scrapper.(scrappers.ContestScrapper); ok {
+		contests, err := cs.GetContests()
+		if err != nil {
+			scrapeErr = err
+		} else if err = updateParticipations(uid, site, contests); err != nil {
+			return err
+		}
+	}
+	partialNode := "fetch_status." + site + ".partial_error"
+	change := bson.M{"$set": update}
+	if scrapeErr != nil {
+		log.Println(scrapeErr.Error())
+		update[partialNode] = scrapeErr.Error()
+	} else {
+		change["$unset"] = bson.M{partialNode: ""}
+	}
+	return coll.UpdateId(uid, change)
 }
 
 func GetProfiles(ID bson.ObjectId) (types.AllProfiles, error) {
@@ -70,54 +115,38 @@ func CompareUser(uid1 bson.ObjectId, uid2 bson.ObjectId) (types.AllWorldRanks, e
 			fmt.Errorf("Could not get user: %s\n%s", err1, err2)
 	}
 
-	return types.AllWorldRanks{
-		CodechefWorldRanks: types.WorldRankComparison{
-			WorldRank1: p1.CodechefProfile.WorldRank,
-			WorldRank2: p2.CodechefProfile.WorldRank,
-		},
-		CodeforcesWorldRanks: types.WorldRankComparison{
-			WorldRank1: p1.CodeforcesProfile.WorldRank,
-			WorldRank2: p2.CodeforcesProfile.WorldRank,
-		},
-		HackerrankWorldRanks: types.WorldRankComparison{
-			WorldRank1: p1.HackerrankProfile.WorldRank,
-			WorldRank2: p2.HackerrankProfile.WorldRank,
-		},
-		SpojWorldRanks: types.WorldRankComparison{
-			WorldRank1: p1.SpojProfile.WorldRank,
-			WorldRank2: p2.SpojProfile.WorldRank,
-		},
-	}, nil
-
+	worldRanks := types.AllWorldRanks{Sites: map[string]types.WorldRankComparison{}}
+	for _, site := range scrappers.Sites() {
+		worldRanks.Sites[site.Name] = types.WorldRankComparison{
+			WorldRank1: p1.Get(site.Name).WorldRank,
+			WorldRank2: p2.Get(site.Name).WorldRank,
+		}
+	}
+	worldRanks.CodechefWorldRanks = worldRanks.Sites["codechef"]
+	worldRanks.CodeforcesWorldRanks = worldRanks.Sites["codeforces"]
+	worldRanks.HackerrankWorldRanks = worldRanks.Sites["hackerrank"]
+	worldRanks.SpojWorldRanks = worldRanks.Sites["spoj"]
+	return worldRanks, nil
 }
 
-func getCorrectIncorrectCount(uid bson.ObjectId, websiteUrl string, correctSubmissionIdentifier string) (int, int, error) {
-	sess := db.NewUserCollectionSession()
+func getCorrectIncorrectCount(uid bson.ObjectId, site string, correctSubmissionIdentifier string) (int, int, error) {
+	sess := db.NewSubmissionCollectionSession()
 	defer sess.Close()
 	coll := sess.Collection
 	match := bson.M{"$match": bson.M{
-		"_id": uid,
-	}}
-	unwind := bson.M{
-		"$unwind": "$submissions",
-	}
-	match2 := bson.M{"$match": bson.M{
-		"submissions.url": bson.M{"$regex": bson.RegEx{
-			Pattern: "^" + websiteUrl,
-		}},
+		"uid":  uid,
+		"site": site,
 	}}
 	pipe := coll.Pipe([]bson.M{
 		match,
-		unwind,
-		match2,
 		{
 			"$facet": bson.M{
 				"total": []bson.M{{"$count": "total"}},
 				"correct": []bson.M{
-					{"$match": bson.M{"submissions.status": StatusCorrect}},
+					{"$match": bson.M{"status": correctSubmissionIdentifier}},
 					{"$count": "correct"}},
-					},
-				},
+			},
+		},
 	})
 	var result []map[string][]map[string]int
 	err := pipe.All(&result)
@@ -129,19 +158,13 @@ func getCorrectIncorrectCount(uid bson.ObjectId, websiteUrl string, correctSubmi
 
 // GetAccuracy function calculates the accuracy of a particular site and returns it
 func GetAccuracy(uid bson.ObjectId, website string) (string, error) {
-	switch website {
-	case CODECHEF:
-		correct, total, err := getCorrectIncorrectCount(uid, "https://www.codechef.com/", "AC")
-		return fmt.Sprintf("%f", float64(correct)/float64(total)), err
-	case CODEFORCES:
-		correct, total, err := getCorrectIncorrectCount(uid, "http://codeforces.com/", "OK")
-		return fmt.Sprintf("%f", float64(correct)/float64(total)), err
-	case SPOJ:
-		correct, total, err := getCorrectIncorrectCount(uid, "https://www.spoj.com", "accepted")
-		return fmt.Sprintf("%f", float64(correct)/float64(total)), err
-	case HACKERRANK:
-		return "1", nil
-	default:
+	site, ok := scrappers.Lookup(website)
+	if !ok {
 		return "", errors.New("Invalid Website")
 	}
+	if !site.Has(scrappers.Verdicts) {
+		return "", errors.New("accuracy not available")
+	}
+	correct, total, err := getCorrectIncorrectCount(uid, site.Name, StatusCorrect)
+	return fmt.Sprintf("%f", float64(correct)/float64(total)), err
 }
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"sort"
+
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/conf"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+)
+
+// Weights of the signals making up the score of a recommendation
+const (
+	sameInstituteWeight = 5.0
+	// Per followed user following the recommended user
+	followedByWeight = 3.0
+	// Per problem solved by both the users
+	sharedProblemWeight = 0.2
+	// Shared problems counted at most, so that prolific users don't
+	// outweigh the other signals
+	maxSharedProblems = 50
+)
+
+// Candidates considered from each signal
+const candidatesPerSignal = 200
+
+// Solved problems of the user compared with the other users
+const maxComparedProblems = 500
+
+// GetRecommendations suggests the users to follow, highest score first.
+// Users from the same institute, users followed by the followed users and
+// users who solved the same problems are suggested, leaving out the users
+// already followed and the users hidden by a block.
+func GetRecommendations(uid bson.ObjectId, limit int) ([]types.Recommendation, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	coll := sess.Collection
+	var user types.User
+	err := coll.FindId(uid).Select(bson.M{"institute": 1, "followingUsers.f_id": 1}).One(&user)
+	if err != nil {
+		return nil, UserNotFoundError
+	}
+	excluded := []bson.ObjectId{uid}
+	for _, f := range user.FollowingUsers {
+		excluded = append(excluded, f.ID)
+	}
+	following := excluded[1:]
+	hidden, err := getHiddenUIDs(uid)
+	if err != nil {
+		return nil, err
+	}
+	excluded = append(excluded, hidden...)
+	candidates := map[bson.ObjectId]*types.Recommendation{}
+	candidate := func(id bson.ObjectId) *types.Recommendation {
+		if _, ok := candidates[id]; !ok {
+			candidates[id] = &types.Recommendation{}
+		}
+		return candidates[id]
+	}
+
+	if user.Institute != "" {
+		var sameInstitute []struct {
+			ID bson.ObjectId `bson:"_id"`
+		}
+		err = coll.Find(bson.M{"institute": user.Institute, "_id": bson.M{"$nin": excluded}}).
+			Select(bson.M{"_id": 1}).Limit(candidatesPerSignal).All(&sameInstitute)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range sameInstitute {
+			candidate(u.ID).SameInstitute = true
+		}
+	}
+
+	if len(following) > 0 {
+		followedBy, err := getFollowedByFollowing(following, excluded)
+		if err != nil {
+			return nil, err
+		}
+		for id, count := range followedBy {
+			candidate(id).FollowedBy = count
+		}
+	}
+
+	shared, err := getSharedProblemCounts(uid, excluded)
+	if err != nil {
+		return nil, err
+	}
+	for id, count := range shared {
+		candidate(id).SharedProblems = count
+	}
+
+	ids := make([]bson.ObjectId, 0, len(candidates))
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+	var users []types.FollowingUser
+	err = coll.Find(bson.M{"_id": bson.M{"$in": ids}, "verified": true}).Select(
+		bson.M{"_id": 1, "username": 1, "picture": 1,
+			"fullname": 1}).All(&users)
+	if err != nil {
+		return nil, err
+	}
+	recommendations := make([]types.Recommendation, 0, len(users))
+	for _, u := range users {
+		r := *candidates[u.ID]
+		r.FollowingUser = u
+		r.Score = score(r)
+		recommendations = append(recommendations, r)
+	}
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].Score > recommendations[j].Score
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations, nil
+}
+
+func score(r types.Recommendation) float64 {
+	var s float64
+	if r.SameInstitute {
+		s += sameInstituteWeight
+	}
+	s += followedByWeight * float64(r.FollowedBy)
+	shared := r.SharedProblems
+	if shared > maxSharedProblems {
+		shared = maxSharedProblems
+	}
+	return s + sharedProblemWeight*float64(shared)
+}
+
+// Counts, for the users followed by any of the following users, the number
+// of following users following them
+func getFollowedByFollowing(following []bson.ObjectId, excluded []bson.ObjectId) (map[bson.ObjectId]int, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	pipe := sess.Collection.Pipe([]bson.M{
+		{"$match": bson.M{"_id": bson.M{"$in": following}}},
+		{"$unwind": "$followingUsers"},
+		{"$match": bson.M{"followingUsers.f_id": bson.M{"$nin": excluded}}},
+		{"$group": bson.M{"_id": "$followingUsers.f_id", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": candidatesPerSignal},
+	})
+	var res []struct {
+		ID    bson.ObjectId `bson:"_id"`
+		Count int           `bson:"count"`
+	}
+	if err := pipe.All(&res); err != nil {
+		return nil, err
+	}
+	counts := make(map[bson.ObjectId]int, len(res))
+	for _, r := range res {
+		counts[r.ID] = r.Count
+	}
+	return counts, nil
+}
+
+// Counts, for the users who solved the problems solved by the user, the
+// number of such problems. Only the latest solves of the user are compared.
+func getSharedProblemCounts(uid bson.ObjectId, excluded []bson.ObjectId) (map[bson.ObjectId]int, error) {
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	var solved []struct {
+		URL string `bson:"_id"`
+	}
+	err := sess.Collection.Pipe([]bson.M{
+		{"$match": bson.M{"uid": uid, "status": StatusCorrect}},
+		{"$group": bson.M{"_id": "$url", "last": bson.M{"$max": "$created_at"}}},
+		{"$sort": bson.M{"last": -1}},
+		{"$limit": maxComparedProblems},
+	}).All(&solved)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]string, 0, len(solved))
+	for _, s := range solved {
+		if s.URL != "" {
+			urls = append(urls, s.URL)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	pipe := sess.Collection.Pipe([]bson.M{
+		{"$match": bson.M{"url": bson.M{"$in": urls}, "status": StatusCorrect, "uid": bson.M{"$nin": excluded}}},
+		{"$group": bson.M{"_id": bson.M{"uid": "$uid", "url": "$url"}}},
+		{"$group": bson.M{"_id": "$_id.uid", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"count": -1}},
+		{"$limit": candidatesPerSignal},
+	})
+	var res []struct {
+		ID    bson.ObjectId `bson:"_id"`
+		Count int           `bson:"count"`
+	}
+	if err = pipe.All(&res); err != nil {
+		return nil, err
+	}
+	counts := make(map[bson.ObjectId]int, len(res))
+	for _, r := range res {
+		counts[r.ID] = r.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/services/redis"
+	"github.com/mdg-iitr/Codephile/services/worker"
+)
+
+// Activity of a user is recorded at most once in this duration
+const activityResolution = time.Hour
+
+// RefreshSite fetches the new submissions and the profile of the user on
+// the site and records the time of refresh
+func RefreshSite(uid bson.ObjectId, site string, ctx context.Context) error {
+	err := AddSubmissions(uid, site, ctx)
+	if err != nil {
+		return err
+	}
+	err = AddOrUpdateProfile(uid, site, ctx)
+	if err != nil {
+		return err
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	return sess.Collection.UpdateId(uid, bson.M{"$set": bson.M{"refreshed." + site: time.Now().UTC()}})
+}
+
+// Queues the refresh of the site, as done right after the handle is
+// changed or the email is verified, so that the result is recorded and
+// failures are retried by the worker. A job already queued for the site
+// does the refresh instead. If the job can't be queued, the site is
+// refreshed right away and the result is recorded here.
+func queueRefresh(uid bson.ObjectId, site string, ctx context.Context) {
+	err := worker.Enqueue(worker.NewJob(uid, site, RefreshSite))
+	if err == nil || err == ErrJobAlreadyQueued {
+		return
+	}
+	RecordFetchResult(uid, site, RefreshSite(uid, site, ctx), false)
+}
+
+// MarkActive records that the user is using codephile. Users active
+// recently are refreshed more often.
+func MarkActive(uid bson.ObjectId) error {
+	client := redis.GetRedisClient()
+	set, err := client.SetNX("active:"+uid.Hex(), 1, activityResolution).Result()
+	if err != nil || !set {
+		return err
+	}
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	return sess.Collection.UpdateId(uid, bson.M{"$set": bson.M{"last_active": time.Now().UTC()}})
+}
+
+// GetStaleUsers returns the verified users having a handle on the site
+// whose last fetch, or scheduling of one, is older than activeInterval if
+// they were active after activeSince, or older than inactiveInterval
+// otherwise. Users whose last fetch failed on every attempt are backed off
+// to inactiveInterval, and the ones whose handle wasn't found are left out
+// till the handle is changed.
+// Active users come first, followed by the ones fetched earliest.
+func GetStaleUsers(site string, activeSince time.Time, activeInterval time.Duration,
+	inactiveInterval time.Duration, limit int) ([]bson.ObjectId, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	now := time.Now().UTC()
+	node := "fetch_status." + site + "."
+	// Matches the users never fetched too
+	notSince := func(t time.Time) bson.M {
+		return bson.M{"$not": bson.M{"$gte": t}}
+	}
+	filter := bson.M{
+		"verified":          true,
+		"handle." + site:    bson.M{"$exists": true, "$ne": ""},
+		node + "error_kind": bson.M{"$ne": string(ScrapeHandleNotFound)},
+		"$or": []bson.M{
+			{
+				"last_active":         bson.M{"$gte": activeSince},
+				node + "result":       bson.M{"$ne": types.FetchFailed},
+				node + "last_attempt": notSince(now.Add(-activeInterval)),
+				node + "scheduled_at": notSince(now.Add(-activeInterval)),
+			},
+			{
+				node + "last_attempt": notSince(now.Add(-inactiveInterval)),
+				node + "scheduled_at": notSince(now.Add(-inactiveInterval)),
+			},
+		},
+	}
+	var users []struct {
+		ID bson.ObjectId `bson:"_id"`
+	}
+	err := sess.Collection.Find(filter).Select(bson.M{"_id": 1}).
+		Sort("-last_active", node+"last_attempt").Limit(limit).All(&users)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]bson.ObjectId, len(users))
+	for i, u := range users {
+		uids[i] = u.ID
+	}
+	return uids, nil
+}
+
+// MarkScheduled records that a fetch of the site is queued for the user,
+// so that the user isn't picked again while the job waits in the queue
+func MarkScheduled(uid bson.ObjectId, site string) error {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	return sess.Collection.UpdateId(uid, bson.M{"$set": bson.M{"fetch_status." + site + ".scheduled_at": time.Now().UTC()}})
+}
@@ -3,23 +3,25 @@ package models
 import (
 	"context"
 	"errors"
-	"fmt"
 	"log"
 	"time"
 
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	. "github.com/mdg-iitr/Codephile/conf"
 	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models/db"
 	"github.com/mdg-iitr/Codephile/models/types"
 	"github.com/mdg-iitr/Codephile/scrappers"
+	_ "github.com/mdg-iitr/Codephile/scrappers/all"
 )
 
 // Fetches Submissions which are made after the lastFetched time, and
 // adds that to the database.
-//Returns HandleNotFoundError/UserNotFoundError/error
+//Returns HandleNotFoundError/UserNotFoundError/ScrapeError/error
 func AddSubmissions(uid bson.ObjectId, site string, ctx context.Context) error {
-	if !IsSiteValid(site) {
+	s, ok := scrappers.Lookup(site)
+	if !ok {
 		return errors.New("site invalid")
 	}
 	sess := db.NewUserCollectionSession()
@@ -32,25 +34,50 @@ func AddSubmissions(uid bson.ObjectId, site string, ctx context.Context) error {
 		return UserNotFoundError
 	}
 	var addSubmissions []types.Submission
-	lastFetched := result["lastfetched"].(map[string]interface{})[site].(time.Time)
-	handle := result["handle"].(map[string]interface{})[site].(string)
+	// sites added later may be missing from older documents
+	lastFetched, _ := result["lastfetched"].(map[string]interface{})[site].(time.Time)
+	handle, _ := result["handle"].(map[string]interface{})[site].(string)
 	scrapper, err := scrappers.NewScrapper(site, handle, ctx)
 	if err != nil {
 		return err
 	}
-	addSubmissions = scrapper.GetSubmissions(lastFetched)
+	addSubmissions, err = scrapper.GetSubmissions(lastFetched)
+	if err != nil {
+		// Stored submissions and lastfetched are left untouched, so that
+		// the next fetch starts from the same point
+		return err
+	}
+	if s.Has(scrappers.Snapshot) {
+		addSubmissions, err = filterStoredSolves(uid, site, addSubmissions)
+		if err != nil {
+			return err
+		}
+	}
 	if len(addSubmissions) != 0 {
 		lastFetched = addSubmissions[0].CreationDate
+		subSess := db.NewSubmissionCollectionSession()
+		defer subSess.Close()
+		bulk := subSess.Collection.Bulk()
+		bulk.Unordered()
+		for _, sub := range addSubmissions {
+			sub.UserID = uid
+			sub.Site = site
+			// Overlapping fetches return already stored submissions again.
+			// Sites not giving ids are told apart by problem and time.
+			selector := bson.M{"uid": uid, "site": site, "id": sub.ID}
+			if sub.ID == "" {
+				selector = bson.M{"uid": uid, "site": site, "url": sub.URL, "created_at": sub.CreationDate}
+			}
+			bulk.Upsert(selector, bson.M{"$setOnInsert": sub})
+		}
+		_, err = bulk.Run()
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
 	}
 
-	change := bson.M{
-		"$push": bson.M{
-			"submissions": bson.M{
-				"$each": addSubmissions,
-				"$sort": bson.M{"created_at": -1},
-			}},
-		"$set": bson.M{"lastfetched." + site: lastFetched}}
-	err = coll.UpdateId(uid, change)
+	err = coll.UpdateId(uid, bson.M{"$set": bson.M{"lastfetched." + site: lastFetched}})
 	if err != nil {
 		log.Println(err.Error())
 		return err
@@ -58,105 +85,189 @@ func AddSubmissions(uid bson.ObjectId, site string, ctx context.Context) error {
 	return nil
 }
 
+// Removes the submissions whose problem is already present among the
+// stored submissions of the site
+func filterStoredSolves(uid bson.ObjectId, site string, subs []types.Submission) ([]types.Submission, error) {
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	var stored []string
+	err := sess.Collection.Find(bson.M{"uid": uid, "site": site}).Distinct("url", &stored)
+	if err != nil {
+		return nil, err
+	}
+	solved := make(map[string]bool)
+	for _, url := range stored {
+		solved[url] = true
+	}
+	var newSubs []types.Submission
+	for _, sub := range subs {
+		if !solved[sub.URL] {
+			newSubs = append(newSubs, sub)
+		}
+	}
+	return newSubs, nil
+}
+
+// DeleteSubmissions removes the stored submissions of the user on a site,
+// or on every site if site is empty, and resets the lastfetched time
 func DeleteSubmissions(uid bson.ObjectId, site string) error {
+	selector := bson.M{"uid": uid}
+	reset := bson.M{}
+	if site != "" {
+		if !scrappers.IsSiteValid(site) {
+			return errors.New("site invalid")
+		}
+		selector["site"] = site
+		reset["lastfetched."+site] = time.Time{}
+	} else {
+		reset["lastfetched"] = types.LastFetchedSubmission{}
+	}
+	subSess := db.NewSubmissionCollectionSession()
+	defer subSess.Close()
+	_, err := subSess.Collection.RemoveAll(selector)
+	if err != nil {
+		return err
+	}
+
 	sess := db.NewUserCollectionSession()
 	defer sess.Close()
-	coll := sess.Collection
-
-	var resetTime time.Time
-	err := coll.UpdateId(uid, bson.M{
-		"$pull": bson.M{
-			"submissions": bson.M{
-				"url": bson.M{
-					"$regex": bson.RegEx{
-						Pattern: "^" + GetRegexSite(site)},
-				}},
-		},
-		"$set": bson.M{"lastfetched." + site: resetTime},
-	})
+	return sess.Collection.UpdateId(uid, bson.M{"$set": reset})
+}
 
-	return err
+// Returns mgo.ErrNotFound if no user has the given uid
+func checkUserExists(uid bson.ObjectId) error {
+	exists, err := UidExists(uid)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return mgo.ErrNotFound
+	}
+	return nil
 }
 
-func GetSubmissions(ID bson.ObjectId, before time.Time) ([]types.Submission, error) {
-	sess := db.NewUserCollectionSession()
+// GetSubmissions returns a page of at most limit submissions of the user
+// after the cursor, latest first
+func GetSubmissions(ID bson.ObjectId, cursor types.Cursor, limit int) (types.SubmissionPage, error) {
+	if err := checkUserExists(ID); err != nil {
+		return types.SubmissionPage{}, err
+	}
+	sess := db.NewSubmissionCollectionSession()
 	defer sess.Close()
-	coll := sess.Collection
-	match := bson.M{
-		"$match": bson.M{
-			"_id": ID,
-		},
-	}
-	project := bson.M{
-		"$project": bson.M{
-			"_id": 0,
-			"submission": bson.M{"$filter": bson.M{
-				"input": "$submissions",
-				"as":    "sub",
-				"cond":  bson.M{"$lt": []interface{}{"$$sub.created_at", before}},
-			},
-			},
-		},
-	}
-	unwind := bson.M{
-		"$unwind": "$submission",
-	}
-	limit := bson.M{
-		"$limit": 100,
-	}
-	group := bson.M{"$group": bson.M{"_id": ID, "submissions": bson.M{"$push": "$submission"}}}
-	pipe := coll.Pipe([]bson.M{
-		match,
-		project,
-		unwind,
-		limit,
-		group,
-	})
-	var res types.User
-	err := pipe.One(&res)
-	return res.Submissions, err
+	subs := []types.Submission{}
+	filter := cursor.Filter()
+	filter["uid"] = ID
+	err := sess.Collection.Find(filter).Sort("-created_at", "-_id").Limit(limit).All(&subs)
+	if err != nil {
+		return types.SubmissionPage{}, err
+	}
+	page := types.SubmissionPage{Data: subs}
+	if len(subs) == limit {
+		last := subs[len(subs)-1]
+		page.NextCursor = types.Cursor{Time: last.CreationDate, ID: last.DocumentID}.String()
+	}
+	return page, nil
 }
+
 func GetAllSubmissions(ID bson.ObjectId) ([]types.Submission, error) {
-	coll := db.NewUserCollectionSession()
-	defer coll.Close()
-	var user types.User
-	err := coll.Collection.FindId(ID).Select(bson.M{"submissions": 1}).One(&user)
+	if err := checkUserExists(ID); err != nil {
+		return nil, err
+	}
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	subs := []types.Submission{}
+	err := sess.Collection.Find(bson.M{"uid": ID}).Sort("-created_at").All(&subs)
+	return subs, err
+}
+
+// Returns the latest n submissions of the user
+func getRecentSubmissions(uid bson.ObjectId, n int) ([]types.Submission, error) {
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	var subs []types.Submission
+	err := sess.Collection.Find(bson.M{"uid": uid}).Sort("-created_at").Limit(n).All(&subs)
+	return subs, err
+}
+
+// Returns the latest n submissions of each of the users, keyed by uid
+func getRecentSubmissionsOf(uids []bson.ObjectId, n int) (map[bson.ObjectId][]types.Submission, error) {
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	pipe := sess.Collection.Pipe([]bson.M{
+		{"$match": bson.M{"uid": bson.M{"$in": uids}}},
+		{"$sort": bson.D{{Name: "uid", Value: 1}, {Name: "created_at", Value: -1}, {Name: "_id", Value: -1}}},
+		{"$group": bson.M{
+			"_id":         "$uid",
+			"submissions": bson.M{"$push": "$$ROOT"},
+		}},
+		{"$project": bson.M{"submissions": bson.M{"$slice": []interface{}{"$submissions", n}}}},
+	}).AllowDiskUse()
+	var res []struct {
+		UID         bson.ObjectId      `bson:"_id"`
+		Submissions []types.Submission `bson:"submissions"`
+	}
+	err := pipe.All(&res)
 	if err != nil {
 		return nil, err
 	}
-	return user.Submissions, nil
+	subs := make(map[bson.ObjectId][]types.Submission, len(res))
+	for _, r := range res {
+		subs[r.UID] = r.Submissions
+	}
+	return subs, nil
 }
 
-//TODO: Return proper errors in FilterSubmission
-func FilterSubmission(uid bson.ObjectId, status string, tag string, site string) ([]map[string]interface{}, error) {
-	c := db.NewUserCollectionSession()
-	defer c.Close()
-	fmt.Println(status)
-	match1 := bson.M{
-		"$match": bson.M{
-			"_id": uid,
-		},
-	}
-	unwind := bson.M{
-		"$unwind": "$submission." + site,
-	}
-	match2 := bson.M{
-		"$match": bson.M{"submission." + site + ".status": status},
-	}
-	project := bson.M{
-		"$project": bson.M{
-			"_id":                0,
-			"submission." + site: 1,
-		},
-	}
-	all := []bson.M{match1, unwind, match2, project}
-	pipe := c.Collection.Pipe(all)
+// Counts the correct submissions of each of the users on every site, keyed by uid
+func getSolvedCounts(uids []bson.ObjectId) (map[bson.ObjectId]types.SolvedProblemsCount, error) {
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	pipe := sess.Collection.Pipe([]bson.M{
+		{"$match": bson.M{"uid": bson.M{"$in": uids}, "status": StatusCorrect}},
+		{"$group": bson.M{
+			"_id":   bson.M{"uid": "$uid", "site": "$site"},
+			"count": bson.M{"$sum": 1},
+		}},
+	})
+	var res []struct {
+		ID struct {
+			UID  bson.ObjectId `bson:"uid"`
+			Site string        `bson:"site"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+	err := pipe.All(&res)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[bson.ObjectId]types.SolvedProblemsCount, len(uids))
+	for _, uid := range uids {
+		solved := types.SolvedProblemsCount{}
+		for _, site := range scrappers.Sites() {
+			solved[site.Name] = 0
+		}
+		counts[uid] = solved
+	}
+	for _, r := range res {
+		if solved, ok := counts[r.ID.UID]; ok {
+			solved[r.ID.Site] = r.Count
+		}
+	}
+	return counts, nil
+}
 
-	var result map[string]interface{}
-	iter := pipe.Iter()
-	var final []map[string]interface{}
-	for iter.Next(&result) {
-		final = append(final, result["submission"].(map[string]interface{})[site].(map[string]interface{}))
+// FilterSubmission returns the submissions of user on a site having the
+// given status and tag, ignoring the filters left empty
+func FilterSubmission(uid bson.ObjectId, status string, tag string, site string) ([]types.Submission, error) {
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	filter := bson.M{"uid": uid, "site": site}
+	if status != "" {
+		filter["status"] = status
+	}
+	if tag != "" {
+		filter["tags"] = tag
 	}
-	return final, nil
+	subs := []types.Submission{}
+	err := sess.Collection.Find(filter).Sort("-created_at").All(&subs)
+	return subs, err
 }
@@ -0,0 +1,58 @@
+package types
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditLookupUser      = "lookup_user"
+	AuditBlacklist       = "blacklist"
+	AuditWhitelist       = "whitelist"
+	AuditDeleteUser      = "delete_user"
+	AuditRefreshUser     = "refresh_user"
+	AuditGrantAdmin      = "grant_admin"
+	AuditRevokeAdmin     = "revoke_admin"
+	AuditViewQueue       = "view_queue"
+	AuditPauseScheduler  = "pause_scheduler"
+	AuditResumeScheduler = "resume_scheduler"
+)
+
+// AuditEntry records an action taken by an admin
+type AuditEntry struct {
+	ID bson.ObjectId `bson:"_id" json:"id"`
+	// Empty for the actions taken with the command line tools
+	Admin  bson.ObjectId `bson:"admin,omitempty" json:"admin,omitempty"`
+	Action string        `bson:"action" json:"action"`
+	// User the action is taken on, if any
+	Target    bson.ObjectId     `bson:"target,omitempty" json:"target,omitempty"`
+	Details   map[string]string `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
+}
+
+// AuditPage is a page of the audit log along with the cursor of the next
+// page, empty if there are no more entries
+type AuditPage struct {
+	Data       []AuditEntry `json:"data"`
+	NextCursor string       `json:"next_cursor"`
+}
+
+// AdminUser is a user as shown to the admins, including the account
+// details hidden from other users
+type AdminUser struct {
+	ID          bson.ObjectId `json:"id"`
+	Username    string        `json:"username"`
+	Email       string        `json:"email"`
+	FullName    string        `json:"fullname"`
+	Institute   string        `json:"institute"`
+	Verified    bool          `json:"verified"`
+	Role        string        `json:"role"`
+	Privacy     string        `json:"privacy"`
+	Handle      Handle        `json:"handle"`
+	OAuth       []string      `json:"oauth"`
+	LastActive  time.Time     `json:"last_active"`
+	Blacklisted bool          `json:"blacklisted"`
+	APIKeys     int           `json:"api_keys"`
+}
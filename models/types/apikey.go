@@ -0,0 +1,50 @@
+package types
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Scope of the admin endpoints, granted only to the admins
+const ScopeAdmin = "admin"
+
+// Scopes granted to the access tokens of a user and, as chosen by the
+// user, to the API keys. Named as in the docs of the endpoints.
+var Scopes = []string{
+	"read:user", "write:user",
+	"read:follow", "write:follow",
+	"read:feed",
+	"read:submission", "write:submission",
+	"read:contests",
+}
+
+// IsScopeValid reports whether an API key can be granted the scope
+func IsScopeValid(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey lets scripts access the APIs on behalf of the user, limited to
+// its scopes. Only the hash of the key is stored.
+type APIKey struct {
+	ID   bson.ObjectId `bson:"_id" json:"id"`
+	UID  bson.ObjectId `bson:"uid" json:"-"`
+	Name string        `bson:"name" json:"name"`
+	// Leading characters of the key, to tell the keys apart
+	Prefix    string    `bson:"prefix" json:"prefix"`
+	Hash      string    `bson:"hash" json:"-"`
+	Scopes    []string  `bson:"scopes" json:"scopes"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	LastUsed  time.Time `bson:"last_used,omitempty" json:"last_used,omitempty"`
+}
+
+// NewAPIKey is a created API key along with the key, which is shown only once
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"strings"
 	"time"
-
-	"github.com/mdg-iitr/Codephile/conf"
 )
 
 type Contest struct {
@@ -75,12 +73,14 @@ func (c *ContestTime) UnmarshalJSON(b []byte) error {
 	return err
 }
 
-func (clistRes CListResult) ToResult() (Result, error) {
+// ToResult converts the clist response into Result. siteFromHost
+// resolves the platform of a contest from its host
+func (clistRes CListResult) ToResult(siteFromHost func(host string) (string, error)) (Result, error) {
 	var result Result
 	currTime := time.Now()
 	result.Timestamp = currTime.Format(time.RFC3339)
 	for _, c := range clistRes.Contests {
-		site, err := conf.GetSiteFromURL(c.Host)
+		site, err := siteFromHost(c.Host)
 		if err != nil {
 			return Result{}, err
 		}
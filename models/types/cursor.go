@@ -0,0 +1,65 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Cursor marks the position in a list ordered by creation time, latest
+// first. The id breaks the ties between the documents created at the same
+// time; a cursor without id starts from the given time.
+type Cursor struct {
+	Time time.Time
+	ID   bson.ObjectId
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// String encodes the cursor into an opaque url safe string
+func (c Cursor) String() string {
+	b := make([]byte, 8, 8+12)
+	binary.BigEndian.PutUint64(b, uint64(c.Time.UnixNano()))
+	b = append(b, []byte(c.ID)...)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor decodes a cursor encoded by Cursor.String
+func ParseCursor(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) != 8+12 {
+		return Cursor{}, errInvalidCursor
+	}
+	return Cursor{
+		Time: time.Unix(0, int64(binary.BigEndian.Uint64(b[:8]))).UTC(),
+		ID:   bson.ObjectId(b[8:]),
+	}, nil
+}
+
+// Filter returns the query selecting the documents after the cursor
+func (c Cursor) Filter() bson.M {
+	if c.ID == "" {
+		return bson.M{"created_at": bson.M{"$lt": c.Time}}
+	}
+	return bson.M{"$or": []bson.M{
+		{"created_at": bson.M{"$lt": c.Time}},
+		{"created_at": c.Time, "_id": bson.M{"$lt": c.ID}},
+	}}
+}
+
+// SubmissionPage is a page of submissions along with the cursor of the
+// next page, empty if there are no more submissions
+type SubmissionPage struct {
+	Data       []Submission `json:"data"`
+	NextCursor string       `json:"next_cursor"`
+}
+
+// FeedPage is a page of the feed along with the cursor of the next page,
+// empty if there are no more submissions
+type FeedPage struct {
+	Data       []FeedObject `json:"data"`
+	NextCursor string       `json:"next_cursor"`
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCursor(t *testing.T) {
+	Convey("Cursor", t, func() {
+		Convey("Round trip", func() {
+			c := Cursor{Time: time.Date(2020, 1, 14, 12, 0, 0, 123, time.UTC), ID: bson.NewObjectId()}
+			parsed, err := ParseCursor(c.String())
+			So(err, ShouldBeNil)
+			So(parsed.Time.Equal(c.Time), ShouldBeTrue)
+			So(parsed.ID, ShouldEqual, c.ID)
+		})
+		Convey("Invalid cursor", func() {
+			_, err := ParseCursor("not a cursor")
+			So(err, ShouldNotBeNil)
+			_, err = ParseCursor(Cursor{Time: time.Now()}.String())
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -2,6 +2,8 @@ package types
 
 import (
 	// "errors"
+	"time"
+
 	"github.com/globalsign/mgo/bson"
 	// "github.com/mdg-iitr/Codephile/models/db"
 	// "github.com/mdg-iitr/Codephile/models"
@@ -11,11 +13,30 @@ type Following struct {
 	ID bson.ObjectId `bson:"f_id" json:"f_id"`
 }
 
+// Request to follow a user whose profile is not public
+type FollowRequest struct {
+	ID   bson.ObjectId `bson:"f_id" json:"f_id"`
+	Time time.Time     `bson:"time" json:"time"`
+}
+
 type FollowingUser struct {
 	ID       bson.ObjectId `bson:"_id" json:"_id"`
 	Username string        `bson:"username" json:"username" schema:"username"`
 	FullName string        `bson:"fullname" json:"fullname" schema:"fullname"`
 	Picture  string        `bson:"picture" json:"picture"`
+	// Whether the user and the listed user follow each other
+	Mutual bool `bson:"-" json:"mutual"`
+}
+
+// User suggested to follow, along with the reasons of suggestion
+type Recommendation struct {
+	FollowingUser
+	Score         float64 `json:"score"`
+	SameInstitute bool    `json:"same_institute"`
+	// Number of followed users following the suggested user
+	FollowedBy int `json:"followed_by"`
+	// Number of problems solved by both the users
+	SharedProblems int `json:"shared_problems"`
 }
 
 type WorldRankComparison struct {
@@ -23,9 +44,12 @@ type WorldRankComparison struct {
 	WorldRank2 string `bson:"rank2" json:"rank2"`
 }
 
+// World ranks of both users. Sites holds the ranks of every site keyed
+// by site name, the other fields are kept for the older clients.
 type AllWorldRanks struct {
-	CodechefWorldRanks   WorldRankComparison `bson:"codechef_ranks" json:"codechef_ranks"`
-	CodeforcesWorldRanks WorldRankComparison `bson:"codeforces_ranks" json:"codeforces_ranks"`
-	HackerrankWorldRanks WorldRankComparison `bson:"hackerrank_ranks" json:"hackerrank_ranks"`
-	SpojWorldRanks       WorldRankComparison `bson:"spoj_ranks" json:"spoj_ranks"`
+	CodechefWorldRanks   WorldRankComparison            `bson:"codechef_ranks" json:"codechef_ranks"`
+	CodeforcesWorldRanks WorldRankComparison            `bson:"codeforces_ranks" json:"codeforces_ranks"`
+	HackerrankWorldRanks WorldRankComparison            `bson:"hackerrank_ranks" json:"hackerrank_ranks"`
+	SpojWorldRanks       WorldRankComparison            `bson:"spoj_ranks" json:"spoj_ranks"`
+	Sites                map[string]WorldRankComparison `bson:"sites" json:"sites"`
 }
@@ -0,0 +1,26 @@
+package types
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Performance of a user in a contest
+type ContestParticipation struct {
+	UserID         bson.ObjectId `bson:"uid" json:"uid"`
+	Site           string        `bson:"site" json:"site"`
+	ContestID      string        `bson:"contest_id" json:"contest_id"`
+	ContestName    string        `bson:"contest_name" json:"contest_name"`
+	Rank           int           `bson:"rank" json:"rank"`
+	Rating         int           `bson:"rating,omitempty" json:"rating,omitempty"`
+	RatingChange   int           `bson:"rating_change" json:"rating_change"`
+	ProblemsSolved int           `bson:"problems_solved,omitempty" json:"problems_solved,omitempty"`
+	Time           time.Time     `bson:"time" json:"time"`
+}
+
+// Participation of a followed user in a contest
+type FollowingParticipation struct {
+	ContestParticipation `bson:",inline"`
+	User                 FollowingUser `bson:"user" json:"user"`
+}
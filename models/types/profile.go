@@ -3,6 +3,9 @@ package types
 import (
 	"encoding/json"
 	"errors"
+	"strings"
+
+	"github.com/globalsign/mgo/bson"
 )
 
 type ProfileInfo struct {
@@ -11,15 +14,49 @@ type ProfileInfo struct {
 	School    string `bson:"school" json:"school" schema:"school"`
 	WorldRank string `bson:"rank" json:"rank" schema:"rank"`
 	Accuracy  string `bson:"accuracy" json:"accuracy" schema:"accuracy"`
+	Rating    string `bson:"rating,omitempty" json:"rating,omitempty" schema:"rating"`
+}
+
+// Suffix of the site name in the keys the profiles are stored and sent by
+const profileSuffix = "Profile"
+
+// Profile of the user on each site, keyed by site name. Stored and sent
+// keyed by <site>Profile.
+type AllProfiles map[string]ProfileInfo
+
+// Get returns the profile of the given site
+func (p AllProfiles) Get(site string) ProfileInfo {
+	return p[site]
+}
+
+func (p AllProfiles) GetBSON() (interface{}, error) {
+	doc := bson.M{}
+	for site, profile := range p {
+		doc[site+profileSuffix] = profile
+	}
+	return doc, nil
 }
 
-//create an allProfilesStruct
-type AllProfiles struct {
-	CodechefProfile   ProfileInfo `bson:"codechefProfile" json:"codechefProfile"`
-	CodeforcesProfile ProfileInfo `bson:"codeforcesProfile" json:"codeforcesProfile"`
-	HackerrankProfile ProfileInfo `bson:"hackerrankProfile" json:"hackerrankProfile"`
-	SpojProfile       ProfileInfo `bson:"spojProfile" json:"spojProfile"`
-	LeetcodeProfile   ProfileInfo `bson:"leetcodeProfile" json:"leetcodeProfile"`
+func (p *AllProfiles) SetBSON(raw bson.Raw) error {
+	var doc map[string]ProfileInfo
+	if err := raw.Unmarshal(&doc); err != nil {
+		return err
+	}
+	*p = AllProfiles{}
+	for key, profile := range doc {
+		(*p)[strings.TrimSuffix(key, profileSuffix)] = profile
+	}
+	return nil
+}
+
+// MarshalJSON lists every registered site, with an empty profile if not
+// fetched, as clients expect every site to be present
+func (p AllProfiles) MarshalJSON() ([]byte, error) {
+	all := map[string]ProfileInfo{}
+	for _, site := range sites() {
+		all[site+profileSuffix] = p[site]
+	}
+	return json.Marshal(all)
 }
 
 //UnmarshalJSON implements the unmarshaler interface for CodeforcesProfileInfo
@@ -42,13 +79,8 @@ func (data *ProfileInfo) UnmarshalJSON(b []byte) error {
 	return err
 }
 
-type SolvedProblemsCount struct {
-	Codechef   int `json:"codechef"`
-	Codeforces int `json:"codeforces"`
-	Hackerrank int `json:"hackerrank"`
-	Spoj       int `json:"spoj"`
-	Leetcode   int `json:"leetcode"`
-}
+// No of problems solved, keyed by site name
+type SolvedProblemsCount map[string]int
 
 type CodechefProfileInfo struct {
 	Status string                 `json:"status"`
@@ -85,6 +117,7 @@ type LeetcodeProfile struct {
 	RealName string
 	School   string
 	Ranking  float64
+	AboutMe  string
 }
 
 type LeetcodeSubmitStats struct {
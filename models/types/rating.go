@@ -0,0 +1,66 @@
+package types
+
+import "time"
+
+// Rating of a user after a rated contest
+type RatingChange struct {
+	Contest string    `json:"contest" bson:"contest"`
+	Rating  int       `json:"rating" bson:"rating"`
+	Rank    int       `json:"rank" bson:"rank"`
+	Time    time.Time `json:"time" bson:"time"`
+}
+
+// Rating changes of a user on a site ordered by time
+type RatingHistory []RatingChange
+
+// Rating history of a user, keyed by site name
+type AllRatings map[string]RatingHistory
+
+type CodeforcesRatingChanges struct {
+	Status string `json:"status"`
+	Result []struct {
+		ContestID               int    `json:"contestId"`
+		ContestName             string `json:"contestName"`
+		Rank                    int    `json:"rank"`
+		RatingUpdateTimeSeconds int64  `json:"ratingUpdateTimeSeconds"`
+		OldRating               int    `json:"oldRating"`
+		NewRating               int    `json:"newRating"`
+	} `json:"result"`
+}
+
+// Rating as embedded in the codechef profile page
+type CodechefRating struct {
+	Code    string `json:"code"`
+	Rating  string `json:"rating"`
+	Rank    string `json:"rank"`
+	Name    string `json:"name"`
+	EndDate string `json:"end_date"`
+}
+
+type LeetcodeContestHistory struct {
+	Data struct {
+		UserContestRankingHistory []LeetcodeContestEntry `json:"userContestRankingHistory"`
+	} `json:"data"`
+}
+
+type LeetcodeContestEntry struct {
+	Attended       bool    `json:"attended"`
+	Rating         float64 `json:"rating"`
+	Ranking        int     `json:"ranking"`
+	ProblemsSolved int     `json:"problemsSolved"`
+	Contest        struct {
+		Title     string `json:"title"`
+		TitleSlug string `json:"titleSlug"`
+		StartTime int64  `json:"startTime"`
+	} `json:"contest"`
+}
+
+type AtcoderRating struct {
+	IsRated           bool      `json:"IsRated"`
+	Place             int       `json:"Place"`
+	OldRating         int       `json:"OldRating"`
+	NewRating         int       `json:"NewRating"`
+	ContestScreenName string    `json:"ContestScreenName"`
+	ContestName       string    `json:"ContestName"`
+	EndTime           time.Time `json:"EndTime"`
+}
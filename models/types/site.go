@@ -0,0 +1,27 @@
+package types
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	siteNamesMu sync.RWMutex
+	siteNames   []string
+)
+
+// RegisterSiteName adds the site to the ones every type keyed by site
+// lists in its JSON. Called by the scrappers registry.
+func RegisterSiteName(name string) {
+	siteNamesMu.Lock()
+	defer siteNamesMu.Unlock()
+	siteNames = append(siteNames, name)
+	sort.Strings(siteNames)
+}
+
+// Returns the names of the registered sites, sorted
+func sites() []string {
+	siteNamesMu.RLock()
+	defer siteNamesMu.RUnlock()
+	return append([]string{}, siteNames...)
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/globalsign/mgo/bson"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSiteKeyedTypes(t *testing.T) {
+	siteNamesMu.Lock()
+	siteNames = []string{"codechef", "codeforces"}
+	siteNamesMu.Unlock()
+
+	Convey("Site keyed types", t, func() {
+		Convey("Handle lists every site in JSON", func() {
+			var h Handle
+			h.Set("codeforces", "tourist")
+			b, err := json.Marshal(h)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, `{"codechef":"","codeforces":"tourist"}`)
+		})
+		Convey("Profiles are stored keyed by <site>Profile", func() {
+			p := AllProfiles{"codeforces": {UserName: "tourist"}}
+			raw, err := bson.Marshal(bson.M{"profiles": p})
+			So(err, ShouldBeNil)
+			var doc bson.M
+			So(bson.Unmarshal(raw, &doc), ShouldBeNil)
+			So(doc["profiles"], ShouldContainKey, "codeforcesProfile")
+
+			var user struct {
+				Profiles AllProfiles `bson:"profiles"`
+			}
+			So(bson.Unmarshal(raw, &user), ShouldBeNil)
+			So(user.Profiles.Get("codeforces").UserName, ShouldEqual, "tourist")
+		})
+		Convey("Profiles list every site in JSON", func() {
+			b, err := json.Marshal(AllProfiles{})
+			So(err, ShouldBeNil)
+			var m map[string]interface{}
+			So(json.Unmarshal(b, &m), ShouldBeNil)
+			So(m, ShouldContainKey, "codechefProfile")
+			So(m, ShouldContainKey, "codeforcesProfile")
+		})
+	})
+}
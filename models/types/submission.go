@@ -2,6 +2,8 @@ package types
 
 import (
 	"time"
+
+	"github.com/globalsign/mgo/bson"
 )
 
 type Submission struct {
@@ -13,6 +15,12 @@ type Submission struct {
 	Points       int       `json:"points" bson:"points"`
 	Tags         []string  `json:"tags" bson:"tags"`
 	Rating       int       `json:"rating" bson:"rating"`
+	// Identifier of the submission on the site
+	ID     string        `json:"id,omitempty" bson:"id,omitempty"`
+	UserID bson.ObjectId `json:"-" bson:"uid,omitempty"`
+	// Identifier of the stored submission, used for pagination
+	DocumentID bson.ObjectId `json:"-" bson:"_id,omitempty"`
+	Site       string        `json:"site,omitempty" bson:"site,omitempty"`
 }
 
 type HackerrankSubmisson struct {
@@ -44,7 +52,26 @@ type Result2 struct {
 	Data Data `json:"data"`
 }
 type LeetcodeSubmissions struct {
-	Title     string `json:"name" bson:"name"`
-	URL       string `json:"url" bson:"url"`
+	ID        string `json:"id" bson:"id"`
+	Title     string `json:"title" bson:"name"`
+	URL       string `json:"titleSlug" bson:"url"`
 	TimeSTamp string `json:"timestamp" bson:"timestamp"`
+	Status    string `json:"statusDisplay" bson:"status"`
+	Language  string `json:"lang" bson:"language"`
+}
+
+type LeetcodeRecentSubmissions struct {
+	Data struct {
+		RecentSubmissionList []LeetcodeSubmissions `json:"recentSubmissionList"`
+	} `json:"data"`
+}
+
+type AtcoderSubmission struct {
+	ID          int     `json:"id"`
+	EpochSecond int64   `json:"epoch_second"`
+	ProblemID   string  `json:"problem_id"`
+	ContestID   string  `json:"contest_id"`
+	Language    string  `json:"language"`
+	Point       float64 `json:"point"`
+	Result      string  `json:"result"`
 }
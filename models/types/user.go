@@ -17,28 +17,121 @@ type User struct {
 	Password            string                `bson:"password" json:"-" schema:"password"`
 	Picture             string                `bson:"picture" json:"picture"`
 	Verified            bool                  `bson:"verified" schema:"-" json:"-"`
-	Handle              Handle                `bson:"handle" json:"handle" schema:"handle"`
-	Submissions         []Submission          `bson:"submissions" json:"recent_submissions" schema:"-"`
+	Handle              Handle                `bson:"handle" json:"handle" schema:"-"`
+	VerifiedHandles     Handle                `bson:"verified_handles" json:"verified_handles" schema:"-"`
+	Submissions         []Submission          `bson:"submissions,omitempty" json:"recent_submissions" schema:"-"`
 	Profiles            AllProfiles           `json:"profiles" bson:"profiles" schema:"-"`
 	Last                LastFetchedSubmission `bson:"lastfetched" json:"-"`
+	Refreshed           LastRefreshed         `bson:"refreshed" json:"-" schema:"-"`
+	LastActive          time.Time             `bson:"last_active,omitempty" json:"-" schema:"-"`
+	Ratings             AllRatings            `bson:"ratings,omitempty" json:"-" schema:"-"`
+	FetchStatus         FetchStatuses         `bson:"fetch_status,omitempty" json:"-" schema:"-"`
+	Privacy             string                `bson:"privacy,omitempty" json:"privacy" schema:"privacy"`
 	FollowingUsers      []Following           `bson:"followingUsers" json:"-"`
+	FollowRequests      []FollowRequest       `bson:"followRequests,omitempty" json:"-" schema:"-"`
+	Blocked             []bson.ObjectId       `bson:"blocked,omitempty" json:"-" schema:"-"`
+	Muted               []bson.ObjectId       `bson:"muted,omitempty" json:"-" schema:"-"`
+	OAuth               map[string]string     `bson:"oauth,omitempty" json:"-" schema:"-"`
+	Role                string                `bson:"role,omitempty" json:"-" schema:"-"`
 	NoOfFollowing       int                   `bson:"-" json:"no_of_following"`
+	NoOfFollowers       int                   `bson:"-" json:"no_of_followers"`
 	SolvedProblemsCount SolvedProblemsCount   `json:"solved_problems_count"`
 }
-type LastFetchedSubmission struct {
-	Codechef   time.Time `bson:"codechef"`
-	Codeforces time.Time `bson:"codeforces"`
-	Hackerrank time.Time `bson:"hackerrank"`
-	Spoj       time.Time `bson:"spoj"`
-	Leetcode   time.Time `bson:"leetcode"`
+
+// Role of the users allowed to use the admin endpoints. Other users have
+// no role.
+const RoleAdmin = "admin"
+
+// Privacy settings, deciding who can see the profile and activity of a
+// user. Users without the setting are public.
+const (
+	PrivacyPublic = "public"
+	// Only the followers can see, following requires approval
+	PrivacyFollowers = "followers"
+	// Nobody else can see, following requires approval
+	PrivacyPrivate = "private"
+)
+
+// IsPrivacyValid reports whether privacy is one of the privacy settings
+func IsPrivacyValid(privacy string) bool {
+	return privacy == PrivacyPublic || privacy == PrivacyFollowers || privacy == PrivacyPrivate
 }
-type Handle struct {
-	Codeforces  string `bson:"codeforces" json:"codeforces" schema:"codeforces"`
-	Codechef    string `bson:"codechef" json:"codechef" schema:"codechef"`
-	Spoj        string `bson:"spoj" json:"spoj" schema:"spoj"`
-	Hackerrank  string `bson:"hackerrank" json:"hackerrank" schema:"hackerrank"`
-	Hackerearth string `bson:"hackerearth" json:"hackerearth" schema:"hackerearth"`
-	Leetcode    string `bson:"leetcode" json:"leetcode" schema:"leetcode"`
+
+// Time of the latest fetched submission, keyed by site name
+type LastFetchedSubmission map[string]time.Time
+
+// Time of the latest successful refresh, keyed by site name
+type LastRefreshed map[string]time.Time
+
+// Result of the latest fetch of each site, keyed by site name
+type FetchStatuses map[string]FetchStatus
+
+// Results of a fetch
+const (
+	FetchNotAttempted = "not_attempted"
+	FetchSucceeded    = "success"
+	// Fetch failed and will be attempted again
+	FetchRetrying = "retrying"
+	// Fetch failed on every attempt
+	FetchFailed = "failed"
+)
+
+// Error kind of failures other than the ScrapeError
+const FetchErrorInternal = "internal"
+
+type FetchStatus struct {
+	LastAttempt time.Time `bson:"last_attempt" json:"last_attempt"`
+	LastSuccess time.Time `bson:"last_success" json:"last_success"`
+	Result      string    `bson:"result" json:"result"`
+	ErrorKind   string    `bson:"error_kind,omitempty" json:"error_kind,omitempty"`
+	Error       string    `bson:"error,omitempty" json:"error,omitempty"`
+	// Failure to fetch the rating history or contests in the last fetch,
+	// which doesn't fail the fetch
+	PartialError string `bson:"partial_error,omitempty" json:"partial_error,omitempty"`
+	// Time the fetch was last queued by the scheduler
+	ScheduledAt time.Time `bson:"scheduled_at,omitempty" json:"-"`
+	// Whether a fetch of the site is waiting in the job queue
+	Queued bool `bson:"-" json:"queued"`
+}
+
+// Handle of the user on each site, keyed by site name
+type Handle map[string]string
+
+// Get returns the handle of the given site, empty if not set
+func (h Handle) Get(site string) string {
+	return h[site]
+}
+
+// Set sets the handle of the given site
+func (h *Handle) Set(site string, handle string) {
+	if *h == nil {
+		*h = Handle{}
+	}
+	(*h)[site] = handle
+}
+
+// MarshalJSON lists every registered site, with an empty handle if not
+// set, as clients expect every site to be present
+func (h Handle) MarshalJSON() ([]byte, error) {
+	all := map[string]string{}
+	for _, site := range sites() {
+		all[site] = h[site]
+	}
+	return json.Marshal(all)
+}
+
+// Token placed by the user on a site to prove the ownership of a handle
+type OwnershipChallenge struct {
+	Site   string `json:"site"`
+	Handle string `json:"handle"`
+	Token  string `json:"token"`
+	// Problem to which a compilation error can be submitted in the
+	// language instead of placing the token, empty if the site doesn't
+	// support it
+	Problem   string    `json:"problem,omitempty"`
+	Language  string    `json:"language,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 func (u *User) UnmarshalJSON(b []byte) error {
@@ -65,6 +158,9 @@ func (u *User) UnmarshalJSON(b []byte) error {
 	} else {
 		return FieldEmptyError
 	}
+	if val, ok := m["privacy"]; ok {
+		u.Privacy, _ = val.(string)
+	}
 	if val, ok := m["handle"]; ok {
 		d, _ := json.Marshal(val)
 		err = json.Unmarshal(d, &u.Handle)
@@ -17,91 +17,17 @@ import (
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/google/uuid"
-	. "github.com/mdg-iitr/Codephile/conf"
 	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models/db"
 	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
 	"github.com/mdg-iitr/Codephile/services/redis"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var (
-	getCodechefSolvesQuery = bson.M{
-		"$size": bson.M{
-			"$filter": bson.M{
-				"input": "$submissions",
-				"as":    "sub",
-				"cond": bson.M{
-					"$and": []bson.M{
-						{
-							"$regexMatch": bson.M{
-								"input": "$$sub.url",
-								"regex": bson.RegEx{Pattern: "^" + "https://www.codechef.com"},
-							},
-						},
-						{"$eq": []string{"$$sub.status", StatusCorrect}}},
-				},
-			},
-		},
-	}
-	getCodeforcesSolvesQuery = bson.M{
-		"$size": bson.M{
-			"$filter": bson.M{
-				"input": "$submissions",
-				"as":    "sub",
-				"cond": bson.M{
-					"$and": []bson.M{
-						{
-							"$regexMatch": bson.M{
-								"input": "$$sub.url",
-								"regex": bson.RegEx{Pattern: "^" + "http://codeforces.com"},
-							},
-						},
-						{"$eq": []string{"$$sub.status", StatusCorrect}}},
-				},
-			},
-		},
-	}
-	getHackerrankSolvesQuery = bson.M{
-		"$size": bson.M{
-			"$filter": bson.M{
-				"input": "$submissions",
-				"as":    "sub",
-				"cond": bson.M{
-					"$and": []bson.M{
-						{
-							"$regexMatch": bson.M{
-								"input": "$$sub.url",
-								"regex": bson.RegEx{Pattern: "^" + "https://www.hackerrank.com"},
-							},
-						},
-						{"$eq": []string{"$$sub.status", StatusCorrect}}},
-				},
-			},
-		},
-	}
-	getSpojSolvesQuery = bson.M{
-		"$size": bson.M{
-			"$filter": bson.M{
-				"input": "$submissions",
-				"as":    "sub",
-				"cond": bson.M{
-					"$and": []bson.M{
-						{
-							"$regexMatch": bson.M{
-								"input": "$$sub.url",
-								"regex": bson.RegEx{Pattern: "^" + "https://www.spoj.com"},
-							},
-						},
-						{"$eq": []string{"$$sub.status", StatusCorrect}}},
-				},
-			},
-		},
-	}
-	getFollowingCountQuery = bson.M{
-		"$size": "$followingUsers",
-	}
-)
+var getFollowingCountQuery = bson.M{
+	"$size": "$followingUsers",
+}
 
 func AddUser(u types.User) (string, error) {
 	u.ID = bson.NewObjectId()
@@ -132,8 +58,8 @@ func GetUser(uid bson.ObjectId) (*types.User, error) {
 	collection := db.NewUserCollectionSession()
 	defer collection.Close()
 	err := collection.Collection.FindId(uid).Select(bson.M{"_id": 1, "username": 1, "email": 1,
-		"handle": 1, "lastfetched": 1, "profiles": 1,
-		"picture": 1, "fullname": 1, "institute": 1, "submissions": bson.M{"$slice": 5}}).One(&user)
+		"handle": 1, "verified_handles": 1, "lastfetched": 1, "profiles": 1,
+		"picture": 1, "fullname": 1, "institute": 1, "privacy": 1}).One(&user)
 	//fmt.Println(err.Error())
 	if err != nil {
 		return nil, err
@@ -145,14 +71,8 @@ func GetUser(uid bson.ObjectId) (*types.User, error) {
 			},
 		},
 		{
-			"$project": bson.M{
-				"_id":              0,
-				"following":        getFollowingCountQuery,
-				"codechefSolves":   getCodechefSolvesQuery,
-				"codeforcesSolves": getCodeforcesSolvesQuery,
-				"hackerrankSolves": getHackerrankSolvesQuery,
-				"spojSolves":       getSpojSolvesQuery,
-			}},
+			"$project": bson.M{"_id": 0, "following": getFollowingCountQuery},
+		},
 	})
 	var res map[string]int
 	err = pipe.One(&res)
@@ -160,11 +80,19 @@ func GetUser(uid bson.ObjectId) (*types.User, error) {
 		return nil, err
 	}
 	user.NoOfFollowing = res["following"]
-	user.SolvedProblemsCount = types.SolvedProblemsCount{
-		Codechef:   res["codechefSolves"],
-		Codeforces: res["codeforcesSolves"],
-		Hackerrank: res["hackerrankSolves"],
-		Spoj:       res["spojSolves"],
+	followers, err := getFollowerCounts([]bson.ObjectId{uid})
+	if err != nil {
+		return nil, err
+	}
+	user.NoOfFollowers = followers[uid]
+	solved, err := getSolvedCounts([]bson.ObjectId{uid})
+	if err != nil {
+		return nil, err
+	}
+	user.SolvedProblemsCount = solved[uid]
+	user.Submissions, err = getRecentSubmissions(uid, 5)
+	if err != nil {
+		return nil, err
 	}
 	return &user, nil
 }
@@ -174,35 +102,49 @@ func GetAllUsers() ([]types.User, error) {
 	collection := db.NewUserCollectionSession()
 	defer collection.Close()
 	err := collection.Collection.Find(nil).Select(bson.M{"_id": 1, "username": 1, "email": 1,
-		"handle": 1, "lastfetched": 1, "profiles": 1,
-		"picture": 1, "fullname": 1, "institute": 1, "submissions": bson.M{"$slice": 5}}).All(&users)
+		"handle": 1, "verified_handles": 1, "lastfetched": 1, "profiles": 1,
+		"picture": 1, "fullname": 1, "institute": 1, "privacy": 1}).All(&users)
 	if err != nil {
 		return nil, err
 	}
 	pipe := collection.Collection.Pipe([]bson.M{
 		{
-			"$project": bson.M{
-				"_id":              0,
-				"following":        getFollowingCountQuery,
-				"codechefSolves":   getCodechefSolvesQuery,
-				"codeforcesSolves": getCodeforcesSolvesQuery,
-				"hackerrankSolves": getHackerrankSolvesQuery,
-				"spojSolves":       getSpojSolvesQuery,
-			}},
+			"$project": bson.M{"following": getFollowingCountQuery},
+		},
 	})
-	var res []map[string]int
+	var res []struct {
+		ID        bson.ObjectId `bson:"_id"`
+		Following int           `bson:"following"`
+	}
 	err = pipe.All(&res)
 	if err != nil {
 		return nil, err
 	}
+	following := make(map[bson.ObjectId]int, len(res))
+	for _, r := range res {
+		following[r.ID] = r.Following
+	}
+	uids := make([]bson.ObjectId, len(users))
 	for i := range users {
-		users[i].SolvedProblemsCount = types.SolvedProblemsCount{
-			Codechef:   res[i]["codechefSolves"],
-			Codeforces: res[i]["codeforcesSolves"],
-			Hackerrank: res[i]["hackerrankSolves"],
-			Spoj:       res[i]["spojSolves"],
-		}
-		users[i].NoOfFollowing = res[i]["following"]
+		uids[i] = users[i].ID
+	}
+	solved, err := getSolvedCounts(uids)
+	if err != nil {
+		return nil, err
+	}
+	followers, err := getFollowerCounts(uids)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := getRecentSubmissionsOf(uids, 5)
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].SolvedProblemsCount = solved[users[i].ID]
+		users[i].NoOfFollowing = following[users[i].ID]
+		users[i].NoOfFollowers = followers[users[i].ID]
+		users[i].Submissions = recent[users[i].ID]
 	}
 	return users, nil
 }
@@ -218,9 +160,24 @@ func GetHandle(uid bson.ObjectId) (types.Handle, error) {
 	return user.Handle, nil
 }
 
+// Reports whether a user other than uid has verified the ownership of the
+// handle on the site
+func isHandleClaimed(uid bson.ObjectId, site string, handle string) (bool, error) {
+	sess := db.NewUserCollectionSession()
+	defer sess.Close()
+	n, err := sess.Collection.Find(bson.M{"_id": bson.M{"$ne": uid}, "verified_handles." + site: handle}).Count()
+	return n > 0, err
+}
+
+// UpdateUser updates the details and handles of the user. A handle whose
+// ownership was verified by another user can't be linked, unless proven
+// again through ConfirmOwnership.
+// Returns HandleClaimedError in that case
 func UpdateUser(uid bson.ObjectId, uu *types.User, ctx context.Context) (a *types.User, err error) {
 	var updateDoc = bson.M{}
-	newHandle, err := GetHandle(uid)
+	// Verification of the changed handles no longer holds
+	var unsetDoc = bson.M{}
+	oldHandle, err := GetHandle(uid)
 	var UpdatedSites []string
 	if err != nil {
 		log.Println(err.Error())
@@ -235,35 +192,32 @@ func UpdateUser(uid bson.ObjectId, uu *types.User, ctx context.Context) (a *type
 	if uu.FullName != "" {
 		updateDoc["fullname"] = uu.FullName
 	}
-	if uu.Handle.Codechef != "" && uu.Handle.Codechef != newHandle.Codechef {
-		updateDoc["handle.codechef"] = uu.Handle.Codechef
-		newHandle.Codechef = uu.Handle.Codechef
-		UpdatedSites = append(UpdatedSites, CODECHEF)
-	}
-	if uu.Handle.Codeforces != "" && uu.Handle.Codeforces != newHandle.Codeforces {
-		updateDoc["handle.codeforces"] = uu.Handle.Codeforces
-		newHandle.Codeforces = uu.Handle.Codeforces
-		UpdatedSites = append(UpdatedSites, CODEFORCES)
-	}
-	if uu.Handle.Hackerearth != "" {
-		updateDoc["handle.hackerearth"] = uu.Handle.Hackerearth
-		newHandle.Hackerearth = uu.Handle.Hackerearth
-		// UpdatedSites = append(UpdatedSites, HACKEREARTH)
-	}
-	if uu.Handle.Hackerrank != "" && uu.Handle.Hackerrank != newHandle.Hackerrank {
-		updateDoc["handle.hackerrank"] = uu.Handle.Hackerrank
-		newHandle.Hackerrank = uu.Handle.Hackerrank
-		UpdatedSites = append(UpdatedSites, HACKERRANK)
+	if uu.Privacy != "" {
+		updateDoc["privacy"] = uu.Privacy
 	}
-	if uu.Handle.Spoj != "" && uu.Handle.Spoj != newHandle.Spoj {
-		updateDoc["handle.spoj"] = uu.Handle.Spoj
-		newHandle.Spoj = uu.Handle.Spoj
-		UpdatedSites = append(UpdatedSites, SPOJ)
+	for _, site := range scrappers.Sites() {
+		handle := uu.Handle.Get(site.Name)
+		if handle != "" && handle != oldHandle.Get(site.Name) {
+			claimed, err := isHandleClaimed(uid, site.Name, handle)
+			if err != nil {
+				return nil, err
+			}
+			if claimed {
+				return nil, HandleClaimedError
+			}
+			updateDoc["handle."+site.Name] = handle
+			unsetDoc["verified_handles."+site.Name] = ""
+			UpdatedSites = append(UpdatedSites, site.Name)
+		}
 	}
 	if len(updateDoc) != 0 {
 		collection := db.NewUserCollectionSession()
 		defer collection.Close()
-		err = collection.Collection.UpdateId(uid, bson.M{"$set": updateDoc})
+		update := bson.M{"$set": updateDoc}
+		if len(unsetDoc) != 0 {
+			update["$unset"] = unsetDoc
+		}
+		err = collection.Collection.UpdateId(uid, update)
 		if err == mgo.ErrNotFound {
 			return nil, UserNotFoundError
 		} else if err != nil {
@@ -283,14 +237,7 @@ func UpdateUser(uid bson.ObjectId, uu *types.User, ctx context.Context) (a *type
 			if err != nil {
 				hub.CaptureException(err)
 			}
-			err = AddSubmissions(uid, value, ctx)
-			if err != nil {
-				hub.CaptureException(err)
-			}
-			err = AddOrUpdateProfile(uid, value, ctx)
-			if err != nil {
-				hub.CaptureException(err)
-			}
+			queueRefresh(uid, value, ctx)
 		}
 	}()
 
@@ -339,10 +286,16 @@ func VerifyEmail(uid bson.ObjectId, ctx context.Context) error {
 	} else if err != nil {
 		return err
 	}
+	var user types.User
+	err = coll.FindId(uid).Select(bson.M{"handle": 1}).One(&user)
+	if err != nil {
+		return err
+	}
 	go func() {
-		for _, value := range ValidSites {
-			_ = AddSubmissions(uid, value, ctx)
-			_ = AddOrUpdateProfile(uid, value, ctx)
+		for _, site := range scrappers.Sites() {
+			if user.Handle.Get(site.Name) != "" {
+				queueRefresh(uid, site.Name, ctx)
+			}
 		}
 	}()
 	return nil
@@ -454,7 +407,13 @@ func PasswordResetEmail(email string, hostName string, ctx context.Context) bool
 	return true
 }
 
-func SearchUser(query string, c int) ([]types.SearchDoc, error) {
+// SearchUser searches the users for the query, leaving out the users
+// hidden from the searching user by a block
+func SearchUser(uid bson.ObjectId, query string, c int) ([]types.SearchDoc, error) {
+	hidden, err := getHiddenUIDs(uid)
+	if err != nil {
+		return nil, err
+	}
 	sess := db.NewUserCollectionSession()
 	defer sess.Close()
 
@@ -471,6 +430,7 @@ func SearchUser(query string, c int) ([]types.SearchDoc, error) {
 			},
 		},
 	}
+	filter := bson.M{"$match": bson.M{"_id": bson.M{"$nin": hidden}}}
 	limit := bson.M{"$limit": c}
 	project := bson.M{
 		"$project": bson.M{
@@ -484,11 +444,12 @@ func SearchUser(query string, c int) ([]types.SearchDoc, error) {
 	}
 	pipe := sess.Collection.Pipe([]bson.M{
 		search,
+		filter,
 		limit,
 		project,
 	})
 	var result []types.SearchDoc
-	err := pipe.All(&result)
+	err = pipe.All(&result)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -528,12 +489,18 @@ func UpdatePassword(uid bson.ObjectId, updatePasswordRequest types.UpdatePasswor
 	return coll.UpdateId(uid, bson.M{"$set": bson.M{"password": string(hash)}})
 }
 
-func FilterUsers(instituteName string) ([]types.SearchDoc, error) {
+// FilterUsers returns the users of the institute, leaving out the users
+// hidden from the requesting user by a block
+func FilterUsers(uid bson.ObjectId, instituteName string) ([]types.SearchDoc, error) {
+	hidden, err := getHiddenUIDs(uid)
+	if err != nil {
+		return nil, err
+	}
 	sess := db.NewUserCollectionSession()
 	defer sess.Close()
 	coll := sess.Collection
 	var result []types.SearchDoc
-	err := coll.Find(bson.M{"institute": instituteName}).Select(bson.M{"_id": 1, "username": 1, "email": 1,
+	err = coll.Find(bson.M{"institute": instituteName, "_id": bson.M{"$nin": hidden}}).Select(bson.M{"_id": 1, "username": 1, "email": 1,
 		"handle": 1, "picture": 1, "fullname": 1, "institute": 1}).All(&result)
 	return result, err
 }
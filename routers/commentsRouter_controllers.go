@@ -7,6 +7,114 @@ import (
 
 func init() {
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "AuditLog",
+            Router: `/audit`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "Queue",
+            Router: `/queue`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "SchedulerStatus",
+            Router: `/scheduler`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "PauseScheduler",
+            Router: `/scheduler/pause`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "ResumeScheduler",
+            Router: `/scheduler/resume`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "LookupUser",
+            Router: `/users/:key`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "DeleteUser",
+            Router: `/users/:uid`,
+            AllowHTTPMethods: []string{"delete"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "GrantAdmin",
+            Router: `/users/:uid/admin`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "RevokeAdmin",
+            Router: `/users/:uid/admin`,
+            AllowHTTPMethods: []string{"delete"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "BlacklistUser",
+            Router: `/users/:uid/blacklist`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "RefreshUser",
+            Router: `/users/:uid/refresh`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:AdminController"],
+        beego.ControllerComments{
+            Method: "WhitelistUser",
+            Router: `/users/:uid/whitelist`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:ContestController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:ContestController"],
         beego.ControllerComments{
             Method: "GetContests",
@@ -25,6 +133,33 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:ContestController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:ContestController"],
+        beego.ControllerComments{
+            Method: "GetParticipations",
+            Router: `/participation`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:ContestController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:ContestController"],
+        beego.ControllerComments{
+            Method: "GetFollowingParticipations",
+            Router: `/participation/:site/:contest_id/following`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:ContestController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:ContestController"],
+        beego.ControllerComments{
+            Method: "GetParticipations",
+            Router: `/participation/:uid`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FeedController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FeedController"],
         beego.ControllerComments{
             Method: "ContestsFeed",
@@ -52,6 +187,24 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "BlockUser",
+            Router: `/block`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "GetBlockedUsers",
+            Router: `/blocked`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
         beego.ControllerComments{
             Method: "CompareUser",
@@ -70,6 +223,15 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "GetFollowers",
+            Router: `/followers`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
         beego.ControllerComments{
             Method: "GetFollowing",
@@ -79,6 +241,69 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "MuteUser",
+            Router: `/mute`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "GetMutedUsers",
+            Router: `/muted`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "GetRecommendations",
+            Router: `/recommendations`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "GetFollowRequests",
+            Router: `/requests`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "AcceptFollowRequest",
+            Router: `/requests/accept`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "RejectFollowRequest",
+            Router: `/requests/reject`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "UnblockUser",
+            Router: `/unblock`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
         beego.ControllerComments{
             Method: "UnFollowUser",
@@ -88,6 +313,15 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:FriendsController"],
+        beego.ControllerComments{
+            Method: "UnmuteUser",
+            Router: `/unmute`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:GraphController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:GraphController"],
         beego.ControllerComments{
             Method: "GetActivityGraph",
@@ -106,6 +340,24 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:GraphController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:GraphController"],
+        beego.ControllerComments{
+            Method: "GetRatingGraph",
+            Router: `/rating/:site`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:GraphController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:GraphController"],
+        beego.ControllerComments{
+            Method: "GetRatingGraph",
+            Router: `/rating/:site/:uid`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:GraphController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:GraphController"],
         beego.ControllerComments{
             Method: "GetStatusCounts",
@@ -124,6 +376,24 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:RankController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:RankController"],
+        beego.ControllerComments{
+            Method: "CodechefRank",
+            Router: `/codechef`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:RankController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:RankController"],
+        beego.ControllerComments{
+            Method: "CodeforcesRank",
+            Router: `/codeforces`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:SubmissionController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:SubmissionController"],
         beego.ControllerComments{
             Method: "PaginatedSubmissions",
@@ -223,6 +493,33 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "CreateAPIKey",
+            Router: `/apikeys`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "GetAPIKeys",
+            Router: `/apikeys`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "DeleteAPIKey",
+            Router: `/apikeys/:id`,
+            AllowHTTPMethods: []string{"delete"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
         beego.ControllerComments{
             Method: "IsAvailable",
@@ -268,6 +565,15 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "FetchStatus",
+            Router: `/fetch/status`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
         beego.ControllerComments{
             Method: "FilterUsers",
@@ -295,6 +601,42 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "OAuthUnlink",
+            Router: `/oauth/:provider`,
+            AllowHTTPMethods: []string{"delete"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "OAuthCallback",
+            Router: `/oauth/:provider/callback`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "OAuthLink",
+            Router: `/oauth/:provider/link`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "OAuthLogin",
+            Router: `/oauth/:provider/login`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
         beego.ControllerComments{
             Method: "PasswordChange",
@@ -340,6 +682,15 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "Refresh",
+            Router: `/refresh`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
         beego.ControllerComments{
             Method: "Search",
@@ -358,6 +709,24 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "Sessions",
+            Router: `/sessions`,
+            AllowHTTPMethods: []string{"get"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "RevokeSession",
+            Router: `/sessions/:sid`,
+            AllowHTTPMethods: []string{"delete"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
     beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
         beego.ControllerComments{
             Method: "CreateUser",
@@ -376,4 +745,22 @@ func init() {
             Filters: nil,
             Params: nil})
 
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "ConfirmOwnership",
+            Router: `/verify/:site/confirm`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
+    beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"] = append(beego.GlobalControllerRouter["github.com/mdg-iitr/Codephile/controllers:UserController"],
+        beego.ControllerComments{
+            Method: "IssueOwnershipToken",
+            Router: `/verify/:site/token`,
+            AllowHTTPMethods: []string{"post"},
+            MethodParams: param.Make(),
+            Filters: nil,
+            Params: nil})
+
 }
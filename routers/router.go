@@ -56,6 +56,11 @@ func init() {
                &controllers.RankController{},              
 			),
 	    ),
+		beego.NSNamespace("/admin",
+			beego.NSInclude(
+				&controllers.AdminController{},
+			),
+		),
 	)
 	beego.SetStaticPath("/static", "static")
 	beego.Router("/", &controllers.HomePageController{})
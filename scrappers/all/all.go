@@ -0,0 +1,15 @@
+// Package all registers every site supported by codephile.
+// Import it for side effects wherever the scrappers registry is used.
+package all
+
+import (
+	_ "github.com/mdg-iitr/Codephile/scrappers/atcoder"
+	_ "github.com/mdg-iitr/Codephile/scrappers/codechef"
+	_ "github.com/mdg-iitr/Codephile/scrappers/codeforces"
+	_ "github.com/mdg-iitr/Codephile/scrappers/cses"
+	_ "github.com/mdg-iitr/Codephile/scrappers/hackerearth"
+	_ "github.com/mdg-iitr/Codephile/scrappers/hackerrank"
+	_ "github.com/mdg-iitr/Codephile/scrappers/kattis"
+	_ "github.com/mdg-iitr/Codephile/scrappers/leetcode"
+	_ "github.com/mdg-iitr/Codephile/scrappers/spoj"
+)
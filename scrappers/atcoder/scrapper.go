@@ -0,0 +1,272 @@
+package atcoder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gocolly/colly"
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/scrappers/common"
+)
+
+// Addresses of atcoder and AtCoder Problems, changed by tests to a local server
+var (
+	baseURL     = "https://atcoder.jp"
+	problemsURL = "https://kenkoooo.com/atcoder"
+)
+
+// Name of the site
+const ATCODER = "atcoder"
+
+type Scrapper struct {
+	Handle  string
+	Context context.Context
+}
+
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:         ATCODER,
+		URLPrefix:    "https://atcoder.jp",
+		Host:         "atcoder.jp",
+		Capabilities: scrappers.Verdicts,
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return Scrapper{Handle: handle, Context: ctx}
+		},
+	})
+}
+
+// AtCoder Problems returns at most 500 submissions per request
+const submissionsPerPage = 500
+
+func (s Scrapper) GetProfileInfo() (types.ProfileInfo, error) {
+	c := common.NewCollector()
+	profile := types.ProfileInfo{Name: s.Handle, UserName: s.Handle}
+
+	c.OnHTML("table.dl-table tr", func(e *colly.HTMLElement) {
+		value := strings.TrimSpace(e.ChildText("td"))
+		switch strings.TrimSpace(e.ChildText("th")) {
+		case "Rank":
+			// Rank is of the form 1234th
+			profile.WorldRank = strings.TrimRight(value, "stndrdth")
+		case "Rating":
+			// Rating might be followed by provisional marker
+			if fields := strings.Fields(value); len(fields) != 0 {
+				profile.Rating = fields[0]
+			}
+		case "Affiliation":
+			profile.School = value
+		}
+	})
+	c.OnHTML("a.username", func(e *colly.HTMLElement) {
+		profile.UserName = strings.TrimSpace(e.Text)
+	})
+
+	var scrapeErr error
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(ATCODER, resp, err)
+	})
+
+	err := c.Visit(baseURL + "/users/" + url.PathEscape(s.Handle))
+	if scrapeErr != nil {
+		return types.ProfileInfo{}, scrapeErr
+	}
+	if err != nil {
+		return types.ProfileInfo{}, common.NetworkError(ATCODER, err)
+	}
+	return profile, nil
+}
+
+// Calls the AtCoder Problems submission API and returns submissions made at or after fromSecond
+func callAtcoderAPI(handle string, fromSecond int64, hub *sentry.Hub) ([]types.AtcoderSubmission, error) {
+	path := fmt.Sprintf("%s/atcoder-api/v3/user/submissions?user=%s&from_second=%d",
+		problemsURL, url.QueryEscape(handle), fromSecond)
+	data, statusCode, err := common.HitGetRequest(path)
+	if err != nil {
+		return nil, common.NetworkError(ATCODER, err)
+	}
+	if statusCode != http.StatusOK {
+		return nil, common.StatusError(ATCODER, statusCode)
+	}
+	var atcoderSubmissions []types.AtcoderSubmission
+	err = json.Unmarshal(data, &atcoderSubmissions)
+	if err != nil {
+		hub.AddBreadcrumb(&sentry.Breadcrumb{
+			Category: "JSON parse error",
+			Message:  string(data),
+		}, nil)
+		hub.CaptureException(err)
+		return nil, common.ParseError(ATCODER, err)
+	}
+	return atcoderSubmissions, nil
+}
+
+func (s Scrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	// Several submissions can be made in the same second, so pages are
+	// requested from the second of the last submission seen. Submissions
+	// seen again are skipped here, and the stored ones when they are added.
+	var fromSecond int64
+	if !after.IsZero() {
+		fromSecond = after.Unix()
+	}
+	var subs []types.Submission
+	seen := make(map[int]bool)
+	for {
+		atcoderSubs, err := callAtcoderAPI(s.Handle, fromSecond, hub)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range atcoderSubs {
+			if seen[result.ID] {
+				continue
+			}
+			seen[result.ID] = true
+			var status string
+			switch result.Result {
+			case "AC":
+				status = StatusCorrect
+			case "CE":
+				status = StatusCompilationError
+			case "RE":
+				status = StatusRuntimeError
+			case "TLE":
+				status = StatusTimeLimitExceeded
+			case "MLE":
+				status = StatusMemoryLimitExceeded
+			default:
+				status = StatusWrongAnswer
+			}
+			subs = append(subs, types.Submission{
+				ID:           strconv.Itoa(result.ID),
+				Name:         result.ProblemID,
+				URL:          baseURL + "/contests/" + result.ContestID + "/tasks/" + result.ProblemID,
+				CreationDate: time.Unix(result.EpochSecond, 0),
+				Status:       status,
+				Language:     result.Language,
+				Points:       int(result.Point),
+			})
+		}
+		if len(atcoderSubs) < submissionsPerPage {
+			break
+		}
+		last := atcoderSubs[len(atcoderSubs)-1].EpochSecond
+		if last <= fromSecond {
+			// The whole page is of a single second, requesting it again
+			// would return the same page
+			break
+		}
+		fromSecond = last
+	}
+	// API returns oldest submission first
+	for i, j := 0, len(subs)-1; i < j; i, j = i+1, j-1 {
+		subs[i], subs[j] = subs[j], subs[i]
+	}
+	return subs, nil
+}
+
+func (s Scrapper) CheckHandle() (bool, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	resp, err := common.Client.Get(baseURL + "/users/" + url.PathEscape(s.Handle))
+	if err != nil {
+		hub.CaptureException(err)
+		return false, common.NetworkError(ATCODER, err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, common.StatusError(ATCODER, resp.StatusCode)
+	}
+}
+
+// Returns the rated contests of the user, oldest first
+func fetchRatedContests(handle string, hub *sentry.Hub) ([]types.AtcoderRating, error) {
+	data, statusCode, err := common.HitGetRequest(baseURL + "/users/" + url.PathEscape(handle) + "/history/json")
+	if err != nil {
+		return nil, common.NetworkError(ATCODER, err)
+	}
+	if statusCode != http.StatusOK {
+		return nil, common.StatusError(ATCODER, statusCode)
+	}
+	var ratings []types.AtcoderRating
+	err = json.Unmarshal(data, &ratings)
+	if err != nil {
+		hub.AddBreadcrumb(&sentry.Breadcrumb{
+			Category: "JSON parse error",
+			Message:  string(data),
+		}, nil)
+		hub.CaptureException(err)
+		return nil, common.ParseError(ATCODER, err)
+	}
+	var rated []types.AtcoderRating
+	for _, r := range ratings {
+		// Unrated participations do not change the rating
+		if r.IsRated {
+			rated = append(rated, r)
+		}
+	}
+	return rated, nil
+}
+
+func (s Scrapper) GetRatingHistory() (types.RatingHistory, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	ratings, err := fetchRatedContests(s.Handle, hub)
+	if err != nil {
+		return nil, err
+	}
+	history := make(types.RatingHistory, len(ratings))
+	for i, r := range ratings {
+		history[i] = types.RatingChange{
+			Contest: r.ContestName,
+			Rating:  r.NewRating,
+			Rank:    r.Place,
+			Time:    r.EndTime,
+		}
+	}
+	return history, nil
+}
+
+func (s Scrapper) GetContests() ([]types.ContestParticipation, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	ratings, err := fetchRatedContests(s.Handle, hub)
+	if err != nil {
+		return nil, err
+	}
+	contests := make([]types.ContestParticipation, len(ratings))
+	for i, r := range ratings {
+		contests[i] = types.ContestParticipation{
+			Site:         ATCODER,
+			ContestID:    strings.SplitN(r.ContestScreenName, ".", 2)[0],
+			ContestName:  r.ContestName,
+			Rank:         r.Place,
+			Rating:       r.NewRating,
+			RatingChange: r.NewRating - r.OldRating,
+			Time:         r.EndTime,
+		}
+	}
+	return contests, nil
+}
@@ -0,0 +1,104 @@
+package atcoder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/scrappers/scrappertest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetSubmissions(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/atcoder-api/v3/user/submissions?user=coder_101&from_second=0":           "submissions_1.json",
+		"/atcoder-api/v3/user/submissions?user=coder_101&from_second=1578136200":  "submissions_2.json",
+		"/atcoder-api/v3/user/submissions?user=busy_coder&from_second=0":          "submissions_same_second.json",
+		"/atcoder-api/v3/user/submissions?user=busy_coder&from_second=1600000000": "submissions_same_second.json",
+	})
+	defer server.Close()
+	problemsURL, baseURL = server.URL, server.URL
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: AtCoder submissions\n", t, func() {
+		Convey("Pages are fetched till a page is not full", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 504)
+			// Latest submission comes first
+			So(subs[0].ID, ShouldEqual, "5006526")
+			So(subs[0].Name, ShouldEqual, "abc183_e")
+			So(subs[0].URL, ShouldEqual, server.URL+"/contests/abc183/tasks/abc183_e")
+			So(subs[0].CreationDate, ShouldEqual, time.Unix(1578138000, 0))
+			So(subs[503].ID, ShouldEqual, "5000000")
+			So(subs[503].Points, ShouldEqual, 100)
+		})
+		Convey("Verdicts are mapped to the common statuses", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			var statuses []string
+			for i := 503; i > 496; i-- {
+				statuses = append(statuses, subs[i].Status)
+			}
+			So(statuses, ShouldResemble, []string{
+				StatusCorrect, StatusWrongAnswer, StatusTimeLimitExceeded, StatusCompilationError,
+				StatusRuntimeError, StatusMemoryLimitExceeded, StatusWrongAnswer,
+			})
+		})
+		Convey("Submissions made in the same second are fetched", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs[3].ID, ShouldEqual, "5006488")
+			So(subs[4].ID, ShouldEqual, "5006487")
+		})
+		Convey("Submissions before after are left out", func() {
+			subs, err := s.GetSubmissions(time.Unix(1578136200, 0))
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 5)
+		})
+		Convey("Fetching stops when a full page is of a single second", func() {
+			subs, err := Scrapper{Handle: "busy_coder", Context: context.Background()}.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 500)
+		})
+	})
+}
+
+func TestProfile(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/users/coder_101":              "profile.html",
+		"/users/coder_101/history/json": "history.json",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: AtCoder profile\n", t, func() {
+		Convey("Profile is parsed", func() {
+			profile, err := s.GetProfileInfo()
+			So(err, ShouldBeNil)
+			So(profile.UserName, ShouldEqual, "coder_101")
+			So(profile.School, ShouldEqual, "IIT Roorkee")
+			So(profile.WorldRank, ShouldEqual, "23417")
+			So(profile.Rating, ShouldEqual, "485")
+			valid, err := s.CheckHandle()
+			So(err, ShouldBeNil)
+			So(valid, ShouldBeTrue)
+			valid, err = Scrapper{Handle: "nosuchuser", Context: context.Background()}.CheckHandle()
+			So(err, ShouldBeNil)
+			So(valid, ShouldBeFalse)
+		})
+		Convey("Unrated contests are left out", func() {
+			contests, err := s.GetContests()
+			So(err, ShouldBeNil)
+			So(contests, ShouldHaveLength, 2)
+			So(contests[1].ContestID, ShouldEqual, "abc151")
+			So(contests[1].Rank, ShouldEqual, 1520)
+			So(contests[1].RatingChange, ShouldEqual, 274)
+			history, err := s.GetRatingHistory()
+			So(err, ShouldBeNil)
+			So(history, ShouldHaveLength, 2)
+		})
+	})
+}
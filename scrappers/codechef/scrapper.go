@@ -7,83 +7,160 @@ import (
 	"fmt"
 	"github.com/getsentry/sentry-go"
 	. "github.com/mdg-iitr/Codephile/conf"
+	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models/types"
-	// "github.com/mdg-iitr/Codephile/scrappers/common"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/scrappers/common"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
-	// "regexp"
-	// "strconv"
-	// "strings"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Name of the site
+const CODECHEF = "codechef"
+
 type Scrapper struct {
 	Handle string
 	Context context.Context
 }
 
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:         CODECHEF,
+		URLPrefix:    "https://www.codechef.com",
+		Host:         "codechef.com",
+		Capabilities: scrappers.Verdicts,
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return Scrapper{Handle: handle, Context: ctx}
+		},
+	})
+}
+
 var token string
 
-func GetBearerToken(hub *sentry.Hub) string {
-	tokenURL := "https://api.codechef.com/oauth/token"
-	resp, err := http.PostForm(tokenURL, map[string][]string{
+// Addresses of codechef, changed by tests to a local server
+var (
+	apiURL  = "https://api.codechef.com"
+	baseURL = "https://www.codechef.com"
+)
+
+func GetBearerToken(hub *sentry.Hub) (string, error) {
+	tokenURL := apiURL + "/oauth/token"
+	resp, err := common.Client.PostForm(tokenURL, map[string][]string{
 		"client_id":     {os.Getenv("CLIENT_ID")},
 		"client_secret": {os.Getenv("CLIENT_SECRET")},
 		"grant_type":    {"client_credentials"},
 		"scope":         {"public"},
 	})
 	if err != nil {
-		log.Println(err.Error())
-		hub.CaptureException(err)
-		return ""
+		return "", common.NetworkError(CODECHEF, err)
 	}
+	defer resp.Body.Close() // nolint: errcheck
 	byteValue, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Println(err.Error())
-		hub.CaptureException(err)
-		return ""
+		return "", common.NetworkError(CODECHEF, err)
+	}
+	var respStruct struct {
+		Result struct {
+			Data struct {
+				AccessToken string `json:"access_token"`
+			} `json:"data"`
+		} `json:"result"`
 	}
-	var respStruct map[string]interface{}
 	err = json.Unmarshal(byteValue, &respStruct)
-	if err != nil {
+	if err != nil || respStruct.Result.Data.AccessToken == "" {
 		hub.AddBreadcrumb(&sentry.Breadcrumb{
-			Category:  "JSON parse error",
-			Message:   string(byteValue),
+			Category: "JSON parse error",
+			Message:  string(byteValue),
 		}, nil)
+		if err == nil {
+			err = errors.New("access token not returned")
+		}
 		hub.CaptureException(err)
+		return "", common.ParseError(CODECHEF, err)
 	}
-	result := respStruct["result"].(map[string]interface{})
-	accessToken := result["data"].(map[string]interface{})["access_token"].(string)
-	return accessToken
+	return respStruct.Result.Data.AccessToken, nil
 }
 
-func fetchAndParseProfileData(handle string, fields string, hub *sentry.Hub) (types.CodechefProfileInfo, int) {
-	profileURL := fmt.Sprintf("https://api.codechef.com/users/%s?fields=%s",
-		handle, url.QueryEscape(fields))
-	client := &http.Client{}
-	req, _ := http.NewRequest(http.MethodGet, profileURL, nil)
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	resp, _ := client.Do(req)
+// Makes a GET request to the API, getting a new token if the current one
+// has expired
+func callAPI(path string, hub *sentry.Hub) ([]byte, error) {
+	var resp *http.Response
+	for attempt := 0; attempt < 2; attempt++ {
+		req, _ := http.NewRequest(http.MethodGet, apiURL+path, nil)
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		var err error
+		resp, err = common.Client.Do(req)
+		if err != nil {
+			return nil, common.NetworkError(CODECHEF, err)
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt == 1 {
+			break
+		}
+		resp.Body.Close() // nolint: errcheck
+		if token, err = GetBearerToken(hub); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close() // nolint: errcheck
 	if resp.StatusCode != http.StatusOK {
-		return types.CodechefProfileInfo{}, resp.StatusCode
+		return nil, common.StatusError(CODECHEF, resp.StatusCode)
 	}
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		hub.CaptureException(err)
+		return nil, common.NetworkError(CODECHEF, err)
 	}
+	return data, nil
+}
+
+// Codes returned in the API response
+const (
+	codeSuccess     = 9001
+	codeRateLimited = 9002
+	codeNotFound    = 9003
+)
+
+// Fetches the profile, retrying while the API limit is exceeded
+func fetchAndParseProfileData(handle string, fields string, hub *sentry.Hub) (types.ProfileInfoContent, error) {
+	path := fmt.Sprintf("/users/%s?fields=%s", url.PathEscape(handle), url.QueryEscape(fields))
 	var profileInfo types.CodechefProfileInfo
-	err = json.Unmarshal(data, &profileInfo)
-	if err != nil {
-		hub.AddBreadcrumb(&sentry.Breadcrumb{
-			Category:  "JSON parse error",
-			Message:   string(data),
-		}, nil)
-		hub.CaptureException(err)
+	for attempt := 0; attempt < 5; attempt++ {
+		time.Sleep(time.Second * time.Duration(attempt))
+		data, err := callAPI(path, hub)
+		if err != nil {
+			return types.ProfileInfoContent{}, err
+		}
+		profileInfo = types.CodechefProfileInfo{}
+		err = json.Unmarshal(data, &profileInfo)
+		if err != nil {
+			hub.AddBreadcrumb(&sentry.Breadcrumb{
+				Category: "JSON parse error",
+				Message:  string(data),
+			}, nil)
+			hub.CaptureException(err)
+			return types.ProfileInfoContent{}, common.ParseError(CODECHEF, err)
+		}
+		if profileInfo.Result["data"].Code != codeRateLimited {
+			break
+		}
+	}
+	result := profileInfo.Result["data"]
+	switch result.Code {
+	case codeSuccess:
+		return result.Content, nil
+	case codeNotFound:
+		return types.ProfileInfoContent{}, common.UnknownHandleError(CODECHEF)
+	case codeRateLimited:
+		return types.ProfileInfoContent{}, NewScrapeError(CODECHEF, ScrapeRateLimited, errors.New(result.Message))
+	default:
+		return types.ProfileInfoContent{}, common.ParseError(CODECHEF, fmt.Errorf("code %d: %s", result.Code, result.Message))
 	}
-	return profileInfo, resp.StatusCode
 }
 
 func (s Scrapper) CheckHandle() (bool, error) {
@@ -91,88 +168,53 @@ func (s Scrapper) CheckHandle() (bool, error) {
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
-	fields := "username"
-	var (
-		profileInfo types.CodechefProfileInfo
-		status      int
-	)
-	for attempt := 0; attempt < 5; attempt++ {
-		time.Sleep(time.Second * time.Duration(attempt))
-		profileInfo, status = fetchAndParseProfileData(s.Handle, fields, hub)
-		if status == http.StatusUnauthorized {
-			token = GetBearerToken(hub)
-			profileInfo, _ = fetchAndParseProfileData(s.Handle, fields, hub) // nolint: ineffassign
-		}
-		// 9002 implies rate limit exceeded
-		if profileInfo.Result["data"].Code != 9002 {
-			break
-		}
+	_, err := fetchAndParseProfileData(s.Handle, "username", hub)
+	if kind, _ := ScrapeErrorKindOf(err); kind == ScrapeHandleNotFound {
+		return false, nil
 	}
-	code := profileInfo.Result["data"].Code
-	return code == 9001, nil
+	return err == nil, err
 }
 
-func (s Scrapper) GetProfileInfo() types.ProfileInfo {
+func (s Scrapper) GetProfileInfo() (types.ProfileInfo, error) {
 	hub := sentry.GetHubFromContext(s.Context)
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
-	fields := "username,fullname,organization,rankings"
-	var (
-		profileInfo types.CodechefProfileInfo
-		status      int
-	)
-	for attempt := 0; attempt < 5; attempt++ {
-		time.Sleep(time.Second * time.Duration(attempt))
-		profileInfo, status = fetchAndParseProfileData(s.Handle, fields, hub)
-		if status == http.StatusUnauthorized {
-			token = GetBearerToken(hub)
-			profileInfo, _ = fetchAndParseProfileData(s.Handle, fields, hub) // nolint: ineffassign
-		}
-		// 9002 implies rate limit exceeded
-		if profileInfo.Result["data"].Code != 9002 {
-			break
-		}
+	resultData, err := fetchAndParseProfileData(s.Handle, "username,fullname,organization,rankings", hub)
+	if err != nil {
+		return types.ProfileInfo{}, err
 	}
-	resultData := profileInfo.Result["data"].Content
-	return types.ProfileInfo{
-		Name:      resultData.Fullname,
-		UserName:  resultData.Username,
-		School:    resultData.Organization,
-		WorldRank: fmt.Sprint(resultData.Rankings["allContestRanking"].(map[string]interface{})["global"].(float64)),
+	profile := types.ProfileInfo{
+		Name:     resultData.Fullname,
+		UserName: resultData.Username,
+		School:   resultData.Organization,
 	}
+	// Users yet to take part in a contest are not ranked
+	if ranking, ok := resultData.Rankings["allContestRanking"].(map[string]interface{}); ok {
+		if global, ok := ranking["global"].(float64); ok {
+			profile.WorldRank = fmt.Sprint(global)
+		}
+	}
+	return profile, nil
 }
 
 func callCodechefAPI(handle string, afterIndex int, hub *sentry.Hub) (types.CodechefSubmissions, error) {
 	fields := "id, date, username, problemCode, language, result"
-	submissionURL := fmt.Sprintf("https://api.codechef.com/submissions/?&username=%s&after=%d&limit=20&fields=%s",
+	path := fmt.Sprintf("/submissions/?&username=%s&after=%d&limit=20&fields=%s",
 		handle, afterIndex, url.QueryEscape(fields))
-	client := &http.Client{}
-	req, _ := http.NewRequest(http.MethodGet, submissionURL, nil)
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
-	resp, _ := client.Do(req)
-	if resp.StatusCode == http.StatusUnauthorized {
-		token = GetBearerToken(hub)
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-		resp, _ = client.Do(req)
-	}
-	data, err := ioutil.ReadAll(resp.Body)
+	data, err := callAPI(path, hub)
 	if err != nil {
-		hub.CaptureException(err)
-	}
-	if data == nil {
-		return types.CodechefSubmissions{}, errors.New("GetRequest failed. Please check connection status")
+		return types.CodechefSubmissions{}, err
 	}
 	var codechefSubmissions types.CodechefSubmissions
 	err = json.Unmarshal(data, &codechefSubmissions)
 	if err != nil {
 		hub.AddBreadcrumb(&sentry.Breadcrumb{
-			Category:  "JSON parse error",
-			Message:   string(data),
+			Category: "JSON parse error",
+			Message:  string(data),
 		}, nil)
 		hub.CaptureException(err)
-		log.Println(err.Error())
-		return types.CodechefSubmissions{}, err
+		return types.CodechefSubmissions{}, common.ParseError(CODECHEF, err)
 	}
 	return codechefSubmissions, nil
 }
@@ -182,24 +224,22 @@ func getCodechefSubmissionParts(handle string, afterIndex int, hub *sentry.Hub)
 	if err != nil {
 		return nil, err, afterIndex
 	}
-	if codechefSubmission.Status != "OK" {
+	for attempt := 1; codechefSubmission.Status != "OK" && attempt < 5; attempt++ {
 		log.Println("Codechef submission could not be retrieved. Retrying...")
-		var newCodechefSub types.CodechefSubmissions
-		for attempt := 1; attempt < 5; attempt++ {
-			time.Sleep(time.Second * time.Duration(attempt))
-			newCodechefSub, err = callCodechefAPI(handle, afterIndex, hub)
-			if err != nil {
-				return nil, err, afterIndex
-			}
-			if newCodechefSub.Status == "OK" {
-				codechefSubmission = newCodechefSub
-				break
-			}
+		time.Sleep(time.Second * time.Duration(attempt))
+		codechefSubmission, err = callCodechefAPI(handle, afterIndex, hub)
+		if err != nil {
+			return nil, err, afterIndex
 		}
-		if newCodechefSub.Status == "FAILED" {
-			hub.CaptureException(errors.New("codechef API repeatedly returned FAILED"))
-			return nil, errors.New("codechef API repeatedly returned FAILED"), 0
+	}
+	if codechefSubmission.Status != "OK" {
+		data := codechefSubmission.Result.Data
+		err = fmt.Errorf("code %d: %s", data.Code, data.Message)
+		if data.Code == codeRateLimited {
+			return nil, NewScrapeError(CODECHEF, ScrapeRateLimited, err), afterIndex
 		}
+		hub.CaptureException(err)
+		return nil, common.ParseError(CODECHEF, err), afterIndex
 	}
 	submissions := make([]types.Submission, len(codechefSubmission.Result.Data.Content))
 	var lastID int
@@ -217,10 +257,11 @@ func getCodechefSubmissionParts(handle string, afterIndex int, hub *sentry.Hub)
 		default:
 			status = StatusWrongAnswer
 		}
+		submissions[i].ID = strconv.Itoa(result.ID)
 		submissions[i].Name = result.ProblemCode
 		submissions[i].Status = status
 		submissions[i].Language = result.Language
-		submissions[i].URL = "https://www.codechef.com/problems/" + result.ProblemCode
+		submissions[i].URL = baseURL + "/problems/" + result.ProblemCode
 		t, err := time.Parse("2006-01-02 15:04:05", result.Date)
 		if err != nil {
 			hub.CaptureException(err)
@@ -231,12 +272,11 @@ func getCodechefSubmissionParts(handle string, afterIndex int, hub *sentry.Hub)
 	return submissions, nil, lastID
 }
 
-func (s Scrapper) GetSubmissions(after time.Time) []types.Submission {
+func (s Scrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
 	hub := sentry.GetHubFromContext(s.Context)
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
-	oldestSubIndex := 0
 	var lastID int
 	var oldestSubFound = false
 	var subs []types.Submission
@@ -246,23 +286,135 @@ func (s Scrapper) GetSubmissions(after time.Time) []types.Submission {
 	for !oldestSubFound {
 		newSub, err, lastID = getCodechefSubmissionParts(s.Handle, lastID, hub)
 		if err != nil {
-			log.Println(err.Error())
-			return nil
+			return nil, err
 		}
 		//Check for repetition of previous fetched submission
 		if len(newSub) != 0 {
 			for _, sub := range newSub {
-				subs = append(subs, sub)
-				oldestSubIndex += 1
+				// Submissions till after are already stored
 				if sub.CreationDate.Equal(after) || sub.CreationDate.Before(after) {
 					oldestSubFound = true
 					break
 				}
+				subs = append(subs, sub)
 			}
 		} else {
 			break
 		}
 	}
-	subs = subs[0:oldestSubIndex]
-	return subs
+	return subs, nil
+}
+
+var allRatingRegex = regexp.MustCompile(`var all_rating = (\[.*?\]);`)
+
+// Codechef API does not expose rating changes, they are read from the
+// rating graph data embedded in the profile page
+func fetchAllRating(handle string, hub *sentry.Hub) ([]types.CodechefRating, error) {
+	data, statusCode, err := common.HitGetRequest(baseURL + "/users/" + url.PathEscape(handle))
+	if err != nil {
+		return nil, common.NetworkError(CODECHEF, err)
+	}
+	if statusCode != http.StatusOK {
+		return nil, common.StatusError(CODECHEF, statusCode)
+	}
+	match := allRatingRegex.FindSubmatch(data)
+	if match == nil {
+		return nil, nil
+	}
+	var ratings []types.CodechefRating
+	err = json.Unmarshal(match[1], &ratings)
+	if err != nil {
+		hub.AddBreadcrumb(&sentry.Breadcrumb{
+			Category: "JSON parse error",
+			Message:  string(match[1]),
+		}, nil)
+		hub.CaptureException(err)
+		return nil, common.ParseError(CODECHEF, err)
+	}
+	return ratings, nil
+}
+
+func (s Scrapper) GetRatingHistory() (types.RatingHistory, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	ratings, err := fetchAllRating(s.Handle, hub)
+	if err != nil {
+		return nil, err
+	}
+	history := make(types.RatingHistory, 0, len(ratings))
+	for _, r := range ratings {
+		rating, err := strconv.Atoi(r.Rating)
+		if err != nil {
+			continue
+		}
+		rank, _ := strconv.Atoi(r.Rank)
+		t, _ := time.Parse("2006-01-02 15:04:05", r.EndDate)
+		history = append(history, types.RatingChange{
+			Contest: r.Name,
+			Rating:  rating,
+			Rank:    rank,
+			Time:    t,
+		})
+	}
+	return history, nil
+}
+
+func (s Scrapper) GetContests() ([]types.ContestParticipation, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	ratings, err := fetchAllRating(s.Handle, hub)
+	if err != nil {
+		return nil, err
+	}
+	contests := make([]types.ContestParticipation, 0, len(ratings))
+	var previous int
+	for _, r := range ratings {
+		rating, err := strconv.Atoi(r.Rating)
+		if err != nil {
+			continue
+		}
+		rank, _ := strconv.Atoi(r.Rank)
+		t, _ := time.Parse("2006-01-02 15:04:05", r.EndDate)
+		contest := types.ContestParticipation{
+			Site:        CODECHEF,
+			ContestID:   r.Code,
+			ContestName: r.Name,
+			Rank:        rank,
+			Rating:      rating,
+			Time:        t,
+		}
+		// Initial rating is not listed, so first contest has no change
+		if previous != 0 {
+			contest.RatingChange = rating - previous
+		}
+		previous = rating
+		contests = append(contests, contest)
+	}
+	return contests, nil
+}
+
+func (s Scrapper) OwnershipProblems() []string {
+	return nil
+}
+
+func (s Scrapper) OwnershipLanguages() []string {
+	return nil
+}
+
+// CheckOwnership looks for the token in the full name and organization
+// of the user
+func (s Scrapper) CheckOwnership(challenge types.OwnershipChallenge) (bool, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	profile, err := fetchAndParseProfileData(s.Handle, "fullname,organization", hub)
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(profile.Fullname+" "+profile.Organization, challenge.Token), nil
 }
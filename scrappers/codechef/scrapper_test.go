@@ -0,0 +1,111 @@
+package codechef
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/scrappers/scrappertest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const submissionFields = "&limit=20&fields=id%2C+date%2C+username%2C+problemCode%2C+language%2C+result"
+
+func TestGetSubmissions(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/submissions/?&username=coder_101&after=0" + submissionFields:        "submissions_1.json",
+		"/submissions/?&username=coder_101&after=29981000" + submissionFields: "submissions_2.json",
+		"/submissions/?&username=coder_101&after=29978000" + submissionFields: "submissions_empty.json",
+	})
+	defer server.Close()
+	apiURL, baseURL = server.URL, server.URL
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: Codechef submissions\n", t, func() {
+		Convey("Pages are fetched after the id of last submission", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 23)
+			So(subs[0].ID, ShouldEqual, "30000000")
+			So(subs[0].Name, ShouldEqual, "FLOW001")
+			So(subs[0].URL, ShouldEqual, server.URL+"/problems/FLOW001")
+			So(subs[0].Language, ShouldEqual, "C++14")
+			So(subs[0].CreationDate, ShouldEqual, time.Date(2020, 1, 20, 18, 0, 0, 0, time.UTC))
+			So(subs[22].ID, ShouldEqual, "29978000")
+		})
+		Convey("Verdicts are mapped to the common statuses", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			var statuses []string
+			for _, sub := range subs[:6] {
+				statuses = append(statuses, sub.Status)
+			}
+			So(statuses, ShouldResemble, []string{
+				StatusCorrect, StatusWrongAnswer, StatusCompilationError,
+				StatusRuntimeError, StatusWrongAnswer, StatusCorrect,
+			})
+		})
+		Convey("Submissions till after are left out", func() {
+			subs, err := s.GetSubmissions(time.Date(2020, 1, 14, 12, 0, 0, 0, time.UTC))
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 21)
+			So(subs[20].CreationDate, ShouldEqual, time.Date(2020, 1, 15, 12, 0, 0, 0, time.UTC))
+		})
+	})
+}
+
+func TestProfile(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/users/coder_101":  "user.json",
+		"/users/nosuchuser": "no_user.json",
+	})
+	defer server.Close()
+	apiURL = server.URL
+
+	Convey("Subject: Codechef profile\n", t, func() {
+		s := Scrapper{Handle: "coder_101", Context: context.Background()}
+		profile, err := s.GetProfileInfo()
+		So(err, ShouldBeNil)
+		So(profile.Name, ShouldEqual, "Coder One")
+		So(profile.UserName, ShouldEqual, "coder_101")
+		So(profile.School, ShouldEqual, "IIT Roorkee")
+		So(profile.WorldRank, ShouldEqual, "4521")
+		valid, err := s.CheckHandle()
+		So(err, ShouldBeNil)
+		So(valid, ShouldBeTrue)
+		valid, err = Scrapper{Handle: "nosuchuser", Context: context.Background()}.CheckHandle()
+		So(err, ShouldBeNil)
+		So(valid, ShouldBeFalse)
+	})
+}
+
+func TestGetContests(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/users/coder_101": "profile.html",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: Codechef contests\n", t, func() {
+		Convey("Rating history is read from the profile page", func() {
+			history, err := s.GetRatingHistory()
+			So(err, ShouldBeNil)
+			So(history, ShouldHaveLength, 3)
+			So(history[0].Contest, ShouldEqual, "January Challenge 2020 Division 2")
+			So(history[0].Rating, ShouldEqual, 1620)
+			So(history[0].Rank, ShouldEqual, 3811)
+			So(history[0].Time, ShouldEqual, time.Date(2020, 1, 13, 15, 0, 0, 0, time.UTC))
+		})
+		Convey("First contest has no rating change", func() {
+			contests, err := s.GetContests()
+			So(err, ShouldBeNil)
+			So(contests, ShouldHaveLength, 3)
+			So(contests[0].ContestID, ShouldEqual, "JAN20B")
+			So(contests[0].RatingChange, ShouldEqual, 0)
+			So(contests[1].RatingChange, ShouldEqual, 68)
+			So(contests[2].RatingChange, ShouldEqual, -33)
+		})
+	})
+}
@@ -3,101 +3,146 @@ package codeforces
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/getsentry/sentry-go"
 
 	. "github.com/mdg-iitr/Codephile/conf"
+	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
 	"github.com/mdg-iitr/Codephile/scrappers/common"
 )
 
+// Number of times a failed API call is made
+const maxAttempts = 3
+
+// Address of codeforces, changed by tests to a local server
+var baseURL = "http://codeforces.com"
+
+// Problems and languages, one of each picked for every token, in which a
+// compilation error is submitted to prove the ownership
+var (
+	ownershipProblems = []string{
+		"/problemset/problem/1/A", "/problemset/problem/4/A", "/problemset/problem/50/A",
+		"/problemset/problem/71/A", "/problemset/problem/96/A", "/problemset/problem/112/A",
+		"/problemset/problem/118/A", "/problemset/problem/158/A", "/problemset/problem/231/A",
+		"/problemset/problem/236/A", "/problemset/problem/263/A", "/problemset/problem/266/A",
+		"/problemset/problem/281/A", "/problemset/problem/282/A", "/problemset/problem/339/A",
+		"/problemset/problem/467/A", "/problemset/problem/546/A", "/problemset/problem/617/A",
+		"/problemset/problem/734/A", "/problemset/problem/791/A",
+	}
+	ownershipLanguages = []string{"GNU C11", "Python 3", "PyPy 3"}
+)
+
+// Name of the site
+const CODEFORCES = "codeforces"
+
+// Submissions looked at to count the problems solved during the contests.
+// Contests older than the oldest of them keep the count stored earlier.
+const contestSolvesWindow = 1000
+
 type Scrapper struct {
 	Handle  string
 	Context context.Context
+	// Rating changes shared by GetRatingHistory and GetContests, so that
+	// they are fetched once per fetch. Not shared if nil.
+	rating *ratingChanges
 }
 
-func (s Scrapper) GetProfileInfo() types.ProfileInfo {
-	hub := sentry.GetHubFromContext(s.Context)
-	if hub == nil {
-		hub = sentry.CurrentHub()
-	}
-	var profile types.ProfileInfo
-	requestUrl := "http://codeforces.com/api/user.info?handles=" + s.Handle
+type ratingChanges struct {
+	once    sync.Once
+	changes types.CodeforcesRatingChanges
+	err     error
+}
+
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:         CODEFORCES,
+		URLPrefix:    "http://codeforces.com",
+		Host:         "codeforces.com",
+		Capabilities: scrappers.Verdicts,
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return Scrapper{Handle: handle, Context: ctx, rating: &ratingChanges{}}
+		},
+	})
+}
+
+// Calls the codeforces API and decodes the response into v. Calls failing
+// due to the API limit are retried.
+func callAPI(path string, v interface{}, hub *sentry.Hub) error {
 	var err error
-	for attempt := 1; attempt < 10; attempt++ {
-		time.Sleep(time.Second * time.Duration(attempt))
-		data, _ := common.HitGetRequest(requestUrl)
-		if data == nil {
-			log.Println(errors.New("GetRequest failed. Please check connection status"))
-			hub.CaptureException(errors.New("GetRequest failed. Please check connection status"))
-		}
-		err = json.Unmarshal(data, &profile)
-		if err == nil {
-			break
+	// Requests are spaced by the rate limiter of common.Client, so failed
+	// attempts are retried right away
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var (
+			data       []byte
+			statusCode int
+		)
+		data, statusCode, err = common.HitGetRequest(baseURL + "/api/" + path)
+		if err != nil {
+			return common.NetworkError(CODEFORCES, err)
 		}
-		log.Println(err.Error())
-		hub.AddBreadcrumb(&sentry.Breadcrumb{
-			Category: "JSON parse error",
-			Message:  string(data),
-		}, nil)
-	}
-	if err != nil {
-		hub.CaptureException(err)
+		var response struct {
+			Status  string `json:"status"`
+			Comment string `json:"comment"`
+		}
+		if json.Unmarshal(data, &response) != nil || response.Status != "OK" {
+			if statusCode == http.StatusServiceUnavailable || statusCode == http.StatusTooManyRequests {
+				err = common.StatusError(CODEFORCES, statusCode)
+				continue
+			}
+			// Comment is of the form "handles: User with handle xyz not found"
+			if strings.HasSuffix(response.Comment, "not found") {
+				return common.UnknownHandleError(CODEFORCES)
+			}
+			if statusCode >= http.StatusInternalServerError {
+				return common.StatusError(CODEFORCES, statusCode)
+			}
+			hub.AddBreadcrumb(&sentry.Breadcrumb{
+				Category: "JSON parse error",
+				Message:  string(data),
+			}, nil)
+			err = fmt.Errorf("status %d: %s", statusCode, response.Comment)
+			hub.CaptureException(err)
+			return common.ParseError(CODEFORCES, err)
+		}
+		if err = json.Unmarshal(data, v); err != nil {
+			hub.AddBreadcrumb(&sentry.Breadcrumb{
+				Category: "JSON parse error",
+				Message:  string(data),
+			}, nil)
+			hub.CaptureException(err)
+			return common.ParseError(CODEFORCES, err)
+		}
+		return nil
 	}
-	return profile
+	return err
 }
 
-// Calls the codeforces submission API and return the response in same format
-func callCodeforcesAPI(handle string, afterIndex int, hub *sentry.Hub) (types.CodeforcesSubmissions, error) {
-	url := "http://codeforces.com/api/user.status?handle=" + handle + "&from=" + strconv.Itoa(afterIndex) + "&count=50"
-	fmt.Println(url)
-	data, statusCode := common.HitGetRequest(url)
-	if data == nil {
-		return types.CodeforcesSubmissions{}, errors.New("GetRequest failed. Please check connection status")
-	}
-	var codeforcesSubmission types.CodeforcesSubmissions
-	err := json.Unmarshal(data, &codeforcesSubmission)
-	if err != nil {
-		hub.AddBreadcrumb(&sentry.Breadcrumb{
-			Category: "JSON parse error",
-			Message:  string(data),
-		}, nil)
-		// Dont unnecessarily report error when API limit exceeds
-		if statusCode != 503 {
-			hub.CaptureException(err)
-		}
-		log.Println(err.Error())
-		return types.CodeforcesSubmissions{}, err
+func (s Scrapper) GetProfileInfo() (types.ProfileInfo, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
 	}
-	return codeforcesSubmission, nil
+	var profile types.ProfileInfo
+	err := callAPI("user.info?handles="+url.QueryEscape(s.Handle), &profile, hub)
+	return profile, err
 }
 
 //Get submissions of a user after an index.
-//Returns an error if unsuccessful
-//On receiving the error caller should return empty submission list
 func getCodeforcesSubmissionParts(handle string, afterIndex int, hub *sentry.Hub) ([]types.Submission, error) {
-	codeforcesSubmission, _ := callCodeforcesAPI(handle, afterIndex, hub)
-	if codeforcesSubmission.Status != "OK" {
-		log.Println("Codeforces submission could not be retrieved. Retrying...")
-		var newCodeforcesSub types.CodeforcesSubmissions
-		for attempt := 1; attempt < 10; attempt++ {
-			time.Sleep(time.Second * time.Duration(attempt))
-			newCodeforcesSub, _ = callCodeforcesAPI(handle, afterIndex, hub)
-			if newCodeforcesSub.Status == "OK" {
-				codeforcesSubmission = newCodeforcesSub
-				break
-			}
-		}
-		if newCodeforcesSub.Status == "" || newCodeforcesSub.Status == "FAILED" {
-			hub.CaptureException(errors.New("codeforces API repeatedly returned FAILED"))
-			return nil, errors.New("codeforces API repeatedly returned FAILED")
-		}
+	var codeforcesSubmission types.CodeforcesSubmissions
+	err := callAPI("user.status?handle="+url.QueryEscape(handle)+"&from="+strconv.Itoa(afterIndex)+"&count=50", &codeforcesSubmission, hub)
+	if err != nil {
+		return nil, err
 	}
 	submissions := make([]types.Submission, len(codeforcesSubmission.Result))
 	for i, result := range codeforcesSubmission.Result {
@@ -123,11 +168,12 @@ func getCodeforcesSubmissionParts(handle string, afterIndex int, hub *sentry.Hub
 		default:
 			status = StatusWrongAnswer
 		}
+		submissions[i].ID = strconv.Itoa(int(result["id"].(float64)))
 		submissions[i].Status = status
 		submissions[i].Language = result["programmingLanguage"].(string)
 		submissions[i].Name = problem["name"].(string)
 		if problem["contestId"] != nil {
-			submissions[i].URL = "http://codeforces.com/problemset/problem/" + strconv.Itoa(int(problem["contestId"].(float64))) + "/" + problem["index"].(string)
+			submissions[i].URL = baseURL + "/problemset/problem/" + strconv.Itoa(int(problem["contestId"].(float64))) + "/" + problem["index"].(string)
 		} else {
 			submissions[i].URL = ""
 		}
@@ -145,39 +191,35 @@ func getCodeforcesSubmissionParts(handle string, afterIndex int, hub *sentry.Hub
 	return submissions, nil
 }
 
-func (s Scrapper) GetSubmissions(after time.Time) []types.Submission {
+func (s Scrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
 	hub := sentry.GetHubFromContext(s.Context)
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
-	var oldestSubIndex, current int
+	var current int
 	var oldestSubFound = false
 	var subs []types.Submission
 	//Fetch submission until oldest submission not found
 	for !oldestSubFound {
 		newSub, err := getCodeforcesSubmissionParts(s.Handle, current+1, hub)
 		if err != nil {
-			log.Println(err.Error())
-			return nil
-		}
-		//Check for repetition of previous fetched submission
-		if len(newSub) != 0 {
-			for i, sub := range newSub {
-				subs = append(subs, sub)
-				oldestSubIndex = current + i + 1
-				if sub.CreationDate.Equal(after) || sub.CreationDate.Before(after) {
-					oldestSubFound = true
-					break
-				}
-			}
-			//50 submissions per page
-			current += 50
-		} else {
+			return nil, err
+		}
+		if len(newSub) == 0 {
 			break
 		}
+		for _, sub := range newSub {
+			// Submissions till after are already stored
+			if sub.CreationDate.Equal(after) || sub.CreationDate.Before(after) {
+				oldestSubFound = true
+				break
+			}
+			subs = append(subs, sub)
+		}
+		//50 submissions per page
+		current += 50
 	}
-	subs = subs[0:oldestSubIndex]
-	return subs
+	return subs, nil
 }
 
 func (s Scrapper) CheckHandle() (bool, error) {
@@ -185,28 +227,156 @@ func (s Scrapper) CheckHandle() (bool, error) {
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
-	data, _ := common.HitGetRequest("http://codeforces.com/api/user.info?handles=" + url.PathEscape(s.Handle))
 	var i interface{}
-	err := json.Unmarshal(data, &i)
+	err := callAPI("user.info?handles="+url.QueryEscape(s.Handle), &i, hub)
+	if kind, _ := ScrapeErrorKindOf(err); kind == ScrapeHandleNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Returns the rating changes of the user, fetched once if shared
+func (s Scrapper) getRatingChanges(hub *sentry.Hub) (types.CodeforcesRatingChanges, error) {
+	fetch := func() (types.CodeforcesRatingChanges, error) {
+		var changes types.CodeforcesRatingChanges
+		err := callAPI("user.rating?handle="+url.QueryEscape(s.Handle), &changes, hub)
+		return changes, err
+	}
+	if s.rating == nil {
+		return fetch()
+	}
+	s.rating.once.Do(func() {
+		s.rating.changes, s.rating.err = fetch()
+	})
+	return s.rating.changes, s.rating.err
+}
+
+func (s Scrapper) GetRatingHistory() (types.RatingHistory, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	changes, err := s.getRatingChanges(hub)
 	if err != nil {
-		for attempt := 1; attempt < 5; attempt++ {
-			time.Sleep(time.Millisecond * time.Duration(attempt) * 100)
-			data, _ := common.HitGetRequest("http://codeforces.com/api/user.info?handles=" + s.Handle)
-			var i interface{}
-			err = json.Unmarshal(data, &i)
-			if err == nil {
-				break
-			}
-			hub.AddBreadcrumb(&sentry.Breadcrumb{
-				Category: "JSON parse error",
-				Message:  string(data),
-			}, nil)
-			log.Println(err.Error())
+		return nil, err
+	}
+	history := make(types.RatingHistory, len(changes.Result))
+	for i, change := range changes.Result {
+		history[i] = types.RatingChange{
+			Contest: change.ContestName,
+			Rating:  change.NewRating,
+			Rank:    change.Rank,
+			Time:    time.Unix(change.RatingUpdateTimeSeconds, 0),
 		}
 	}
+	return history, nil
+}
+
+// Counts the problems solved by the user during each contest, keyed by
+// contest id, among the latest contestSolvesWindow submissions
+func getContestSolves(handle string, hub *sentry.Hub) (map[int]int, error) {
+	var codeforcesSubmission types.CodeforcesSubmissions
+	err := callAPI("user.status?handle="+url.QueryEscape(handle)+"&from=1&count="+strconv.Itoa(contestSolvesWindow), &codeforcesSubmission, hub)
+	if err != nil {
+		return nil, err
+	}
+	solved := make(map[string]bool)
+	solves := make(map[int]int)
+	for _, result := range codeforcesSubmission.Result {
+		author, _ := result["author"].(map[string]interface{})
+		problem, _ := result["problem"].(map[string]interface{})
+		contestID, ok := problem["contestId"].(float64)
+		if !ok || result["verdict"] != "OK" || author["participantType"] != "CONTESTANT" {
+			continue
+		}
+		key := fmt.Sprintf("%d/%v", int(contestID), problem["index"])
+		if !solved[key] {
+			solved[key] = true
+			solves[int(contestID)]++
+		}
+	}
+	return solves, nil
+}
+
+func (s Scrapper) GetContests() ([]types.ContestParticipation, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	changes, err := s.getRatingChanges(hub)
+	if err != nil {
+		return nil, err
+	}
+	solves, err := getContestSolves(s.Handle, hub)
+	if err != nil {
+		// Participation is still worth storing without the solve count
+		log.Println(err.Error())
+	}
+	contests := make([]types.ContestParticipation, len(changes.Result))
+	for i, change := range changes.Result {
+		contests[i] = types.ContestParticipation{
+			Site:           CODEFORCES,
+			ContestID:      strconv.Itoa(change.ContestID),
+			ContestName:    change.ContestName,
+			Rank:           change.Rank,
+			Rating:         change.NewRating,
+			RatingChange:   change.NewRating - change.OldRating,
+			ProblemsSolved: solves[change.ContestID],
+			Time:           time.Unix(change.RatingUpdateTimeSeconds, 0),
+		}
+	}
+	return contests, nil
+}
+
+func (s Scrapper) OwnershipProblems() []string {
+	problems := make([]string, len(ownershipProblems))
+	for i, problem := range ownershipProblems {
+		problems[i] = baseURL + problem
+	}
+	return problems
+}
+
+func (s Scrapper) OwnershipLanguages() []string {
+	return ownershipLanguages
+}
+
+// CheckOwnership looks for the token in the first name, last name and
+// organization of the user, and for a compilation error in the problem and
+// language of the challenge among the latest submissions
+func (s Scrapper) CheckOwnership(challenge types.OwnershipChallenge) (bool, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	var info struct {
+		Result []struct {
+			FirstName    string `json:"firstName"`
+			LastName     string `json:"lastName"`
+			Organization string `json:"organization"`
+		} `json:"result"`
+	}
+	err := callAPI("user.info?handles="+url.QueryEscape(s.Handle), &info, hub)
+	if err != nil {
+		return false, err
+	}
+	for _, user := range info.Result {
+		if strings.Contains(user.FirstName+" "+user.LastName+" "+user.Organization, challenge.Token) {
+			return true, nil
+		}
+	}
+	subs, err := getCodeforcesSubmissionParts(s.Handle, 1, hub)
 	if err != nil {
-		hub.CaptureException(err)
 		return false, err
 	}
-	return i.(map[string]interface{})["status"] != "FAILED", nil
+	for _, sub := range subs {
+		// Submissions are ordered latest first
+		if sub.CreationDate.Before(challenge.IssuedAt) {
+			break
+		}
+		if sub.Status == StatusCompilationError && sub.URL == challenge.Problem &&
+			sub.Language == challenge.Language {
+			return true, nil
+		}
+	}
+	return false, nil
 }
@@ -0,0 +1,199 @@
+package codeforces
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/mdg-iitr/Codephile/conf"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/scrappers/scrappertest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetSubmissions(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/api/user.status?handle=tourist&from=1&count=50":   "user_status_1.json",
+		"/api/user.status?handle=tourist&from=51&count=50":  "user_status_51.json",
+		"/api/user.status?handle=tourist&from=101&count=50": "user_status_empty.json",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "tourist", Context: context.Background()}
+
+	Convey("Subject: Codeforces submissions\n", t, func() {
+		Convey("All pages are fetched when after is zero", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 53)
+			So(subs[0].ID, ShouldEqual, "70001020")
+			So(subs[0].Name, ShouldEqual, "Problem 1300A")
+			So(subs[0].URL, ShouldEqual, server.URL+"/problemset/problem/1300/A")
+			So(subs[0].CreationDate, ShouldEqual, time.Unix(1580000000, 0))
+			So(subs[0].Points, ShouldEqual, 500)
+			So(subs[0].Rating, ShouldEqual, 800)
+			So(subs[0].Tags, ShouldResemble, []string{"greedy", "math"})
+		})
+		Convey("Verdicts are mapped to the common statuses", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			statuses := make([]string, 10)
+			for i := range statuses {
+				statuses[i] = subs[i].Status
+			}
+			So(statuses, ShouldResemble, []string{
+				StatusCorrect, StatusWrongAnswer, StatusTimeLimitExceeded, StatusCorrect, StatusCompilationError,
+				StatusRuntimeError, StatusMemoryLimitExceeded, StatusWrongAnswer, StatusCorrect, StatusPartial,
+			})
+		})
+		Convey("Submissions till after are left out", func() {
+			after := time.Unix(1580000000-51*3600, 0)
+			subs, err := s.GetSubmissions(after)
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 51)
+			So(subs[len(subs)-1].CreationDate, ShouldHappenAfter, after)
+		})
+		Convey("Nothing is returned when the latest submission is already stored", func() {
+			subs, err := s.GetSubmissions(time.Unix(1580000000, 0))
+			So(err, ShouldBeNil)
+			So(subs, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestCheckHandle(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/api/user.info?handles=tourist":    "user_info.json",
+		"/api/user.info?handles=nosuchuser": "user_info_failed.json",
+	})
+	defer server.Close()
+	baseURL = server.URL
+
+	Convey("Subject: Codeforces handle check\n", t, func() {
+		valid, err := Scrapper{Handle: "tourist", Context: context.Background()}.CheckHandle()
+		So(err, ShouldBeNil)
+		So(valid, ShouldBeTrue)
+		valid, err = Scrapper{Handle: "nosuchuser", Context: context.Background()}.CheckHandle()
+		So(err, ShouldBeNil)
+		So(valid, ShouldBeFalse)
+	})
+	Convey("Subject: Codeforces profile of unknown handle\n", t, func() {
+		_, err := Scrapper{Handle: "nosuchuser", Context: context.Background()}.GetProfileInfo()
+		kind, _ := ScrapeErrorKindOf(err)
+		So(kind, ShouldEqual, ScrapeHandleNotFound)
+	})
+}
+
+func TestGetContests(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/api/user.rating?handle=tourist":                   "user_rating.json",
+		"/api/user.status?handle=tourist&from=1&count=1000": "user_status_contest.json",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "tourist", Context: context.Background()}
+
+	Convey("Subject: Codeforces contests\n", t, func() {
+		Convey("Rating history lists every rated contest", func() {
+			history, err := s.GetRatingHistory()
+			So(err, ShouldBeNil)
+			So(history, ShouldHaveLength, 2)
+			So(history[1].Contest, ShouldEqual, "Educational Codeforces Round 80 (Rated for Div. 2)")
+			So(history[1].Rating, ShouldEqual, 3651)
+			So(history[1].Rank, ShouldEqual, 12)
+		})
+		Convey("Problems solved during the contest are counted once", func() {
+			contests, err := s.GetContests()
+			So(err, ShouldBeNil)
+			So(contests, ShouldHaveLength, 2)
+			So(contests[0].RatingChange, ShouldEqual, 62)
+			// Problem of 1281 was solved in practice
+			So(contests[0].ProblemsSolved, ShouldEqual, 0)
+			So(contests[1].ContestID, ShouldEqual, "1288")
+			So(contests[1].RatingChange, ShouldEqual, -11)
+			So(contests[1].ProblemsSolved, ShouldEqual, 2)
+		})
+	})
+}
+
+func TestRatingFetchedOnce(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/api/user.rating?handle=tourist": "user_rating.json",
+	})
+	baseURL = server.URL
+	site, _ := scrappers.Lookup(CODEFORCES)
+	s := site.New("tourist", context.Background())
+
+	Convey("Subject: Codeforces rating changes\n", t, func() {
+		_, err := s.(scrappers.RatingScrapper).GetRatingHistory()
+		So(err, ShouldBeNil)
+		server.Close()
+		Convey("Contests reuse the rating changes fetched for the history", func() {
+			statusServer := scrappertest.NewServer(t, map[string]string{
+				"/api/user.status?handle=tourist&from=1&count=1000": "user_status_contest.json",
+			})
+			defer statusServer.Close()
+			baseURL = statusServer.URL
+			contests, err := s.(scrappers.ContestScrapper).GetContests()
+			So(err, ShouldBeNil)
+			So(contests, ShouldHaveLength, 2)
+			So(contests[1].ProblemsSolved, ShouldEqual, 2)
+		})
+	})
+}
+
+func TestCheckOwnership(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/api/user.info?handles=tourist":                  "user_info.json",
+		"/api/user.status?handle=tourist&from=1&count=50": "user_status_ownership.json",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "tourist", Context: context.Background()}
+
+	challenge := types.OwnershipChallenge{
+		Token:    "codephile-0123456789",
+		Problem:  server.URL + "/problemset/problem/4/A",
+		Language: "GNU C11",
+		IssuedAt: time.Unix(1580003600, 0),
+	}
+
+	Convey("Subject: Codeforces ownership\n", t, func() {
+		Convey("Token in the organization proves the ownership", func() {
+			owned, err := s.CheckOwnership(types.OwnershipChallenge{Token: "ITMO", IssuedAt: time.Unix(1580010000, 0)})
+			So(err, ShouldBeNil)
+			So(owned, ShouldBeTrue)
+		})
+		Convey("Compilation error to the problem in the language after the token is issued proves the ownership", func() {
+			owned, err := s.CheckOwnership(challenge)
+			So(err, ShouldBeNil)
+			So(owned, ShouldBeTrue)
+		})
+		Convey("Compilation error in another language is ignored", func() {
+			other := challenge
+			other.Language = "Python 3"
+			owned, err := s.CheckOwnership(other)
+			So(err, ShouldBeNil)
+			So(owned, ShouldBeFalse)
+		})
+		Convey("Compilation error to another problem is ignored", func() {
+			other := challenge
+			other.Problem = server.URL + "/problemset/problem/1/A"
+			owned, err := s.CheckOwnership(other)
+			So(err, ShouldBeNil)
+			So(owned, ShouldBeFalse)
+		})
+		Convey("Compilation error before the token is issued is ignored", func() {
+			other := challenge
+			other.IssuedAt = time.Unix(1580010000, 0)
+			owned, err := s.CheckOwnership(other)
+			So(err, ShouldBeNil)
+			So(owned, ShouldBeFalse)
+		})
+		Convey("Problems to submit to are on the site", func() {
+			So(s.OwnershipProblems(), ShouldContain, challenge.Problem)
+		})
+	})
+}
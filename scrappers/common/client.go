@@ -0,0 +1,215 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/gocolly/colly"
+)
+
+// ErrCircuitOpen is returned for the requests to a host which failed
+// repeatedly, till the cool down period is over
+var ErrCircuitOpen = errors.New("too many failures, requests to host suspended")
+
+// Transport is shared by all the scrappers. It limits the rate of requests
+// to each host, waits as asked by the Retry-After header and stops
+// requesting a host which keeps failing.
+var Transport = &limitedTransport{
+	base:  http.DefaultTransport,
+	hosts: map[string]*hostState{},
+}
+
+// Client to be used by scrappers for every request
+var Client = &http.Client{
+	Transport: Transport,
+	Timeout:   time.Duration(beego.AppConfig.DefaultInt("SCRAPPER_TIMEOUT", 10)) * time.Second,
+}
+
+// NewCollector returns a colly collector making requests through Transport
+func NewCollector(options ...func(*colly.Collector)) *colly.Collector {
+	c := colly.NewCollector(options...)
+	c.WithTransport(Transport)
+	c.SetRequestTimeout(Client.Timeout)
+	return c
+}
+
+// Limits of a host, read from app.conf. Host specific values are set by
+// suffixing the key with the host e.g. SCRAPPER_RATE_codeforces.com
+type hostConfig struct {
+	// Requests allowed per second
+	rate float64
+	// Requests allowed at once after being idle
+	burst float64
+	// Consecutive failures after which requests are suspended
+	threshold int
+	// Time for which requests are suspended
+	cooldown time.Duration
+	// Longest Retry-After for which a request is retried instead of failing
+	maxRetryAfter time.Duration
+	// Wait after a 429/503 response without a Retry-After header
+	backoff time.Duration
+}
+
+func configFor(host string) hostConfig {
+	float := func(key string, def float64) float64 {
+		return beego.AppConfig.DefaultFloat(key+"_"+host, beego.AppConfig.DefaultFloat(key, def))
+	}
+	seconds := func(key string, def float64) time.Duration {
+		return time.Duration(float(key, def) * float64(time.Second))
+	}
+	return hostConfig{
+		rate:          float("SCRAPPER_RATE", 2),
+		burst:         float("SCRAPPER_BURST", 5),
+		threshold:     int(float("SCRAPPER_BREAKER_THRESHOLD", 5)),
+		cooldown:      seconds("SCRAPPER_BREAKER_COOLDOWN", 60),
+		maxRetryAfter: seconds("SCRAPPER_MAX_RETRY_AFTER", 30),
+		backoff:       seconds("SCRAPPER_BACKOFF", 2),
+	}
+}
+
+// Token bucket and circuit breaker of a host
+type hostState struct {
+	sync.Mutex
+	config hostConfig
+	tokens float64
+	last   time.Time
+	// No request is made before this time, set from Retry-After
+	pausedUntil time.Time
+	failures    int
+	openUntil   time.Time
+}
+
+type limitedTransport struct {
+	base  http.RoundTripper
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+func (t *limitedTransport) host(name string) *hostState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.hosts[name]
+	if !ok {
+		config := configFor(name)
+		h = &hostState{config: config, tokens: config.burst, last: time.Now()}
+		t.hosts[name] = h
+	}
+	return h
+}
+
+// Takes a token, returning the time to wait before making the request
+func (h *hostState) reserve() (time.Duration, error) {
+	h.Lock()
+	defer h.Unlock()
+	now := time.Now()
+	if h.failures >= h.config.threshold && now.Before(h.openUntil) {
+		return 0, ErrCircuitOpen
+	}
+	h.tokens += now.Sub(h.last).Seconds() * h.config.rate
+	if h.tokens > h.config.burst {
+		h.tokens = h.config.burst
+	}
+	h.last = now
+	h.tokens--
+	var wait time.Duration
+	if h.tokens < 0 {
+		wait = time.Duration(-h.tokens / h.config.rate * float64(time.Second))
+	}
+	if pause := h.pausedUntil.Sub(now); pause > wait {
+		wait = pause
+	}
+	return wait, nil
+}
+
+func (h *hostState) record(failed bool) {
+	h.Lock()
+	defer h.Unlock()
+	if !failed {
+		h.failures = 0
+		return
+	}
+	h.failures++
+	if h.failures >= h.config.threshold {
+		h.openUntil = time.Now().Add(h.config.cooldown)
+	}
+}
+
+func (h *hostState) pause(d time.Duration) {
+	h.Lock()
+	defer h.Unlock()
+	if until := time.Now().Add(d); until.After(h.pausedUntil) {
+		h.pausedUntil = until
+	}
+}
+
+// Parses the Retry-After header given either in seconds or as a date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.host(req.URL.Hostname())
+	// Only requests without body can be sent again
+	retried := req.Body != nil && req.GetBody == nil
+	for {
+		wait, err := h.reserve()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", req.URL.Host, err)
+		}
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+		}
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			h.record(true)
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			h.record(resp.StatusCode >= 500)
+			return resp, nil
+		}
+		// Hosts which keep limiting the requests open the circuit too
+		h.record(true)
+		delay, ok := retryAfter(resp)
+		if !ok {
+			delay = h.config.backoff
+		}
+		h.pause(delay)
+		if retried || delay > h.config.maxRetryAfter {
+			return resp, nil
+		}
+		retried = true
+		resp.Body.Close() // nolint: errcheck
+		if req.GetBody != nil {
+			// The request of the caller must not be modified, so the body
+			// is set on a copy. Request.Clone needs go 1.13.
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.WithContext(req.Context())
+			req.Body = body
+		}
+	}
+}
@@ -2,23 +2,19 @@ package common
 
 import (
 	"io/ioutil"
-	"log"
-	"net/http"
-	"time"
 )
 
-func HitGetRequest(path string) ([]byte, int) {
-	client := http.Client{Timeout: time.Second * 10}
-	resp, err := client.Get(path)
+// HitGetRequest returns the body and status code of the response. Error is
+// returned only if the request could not be made.
+func HitGetRequest(path string) ([]byte, int, error) {
+	resp, err := Client.Get(path)
 	if err != nil {
-		log.Println(err)
-		return nil, 0
+		return nil, 0, err
 	}
 	defer resp.Body.Close() // nolint: errcheck
 	byteValue, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		log.Println(err.Error())
-		return nil, 0
+		return nil, 0, err
 	}
-	return byteValue, resp.StatusCode
+	return byteValue, resp.StatusCode, nil
 }
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gocolly/colly"
+	"github.com/mdg-iitr/Codephile/errors"
+)
+
+// NetworkError is returned when the site could not be reached
+func NetworkError(site string, err error) error {
+	return errors.NewScrapeError(site, errors.ScrapeNetwork, err)
+}
+
+// ParseError is returned when the response of the site could not be parsed
+func ParseError(site string, err error) error {
+	return errors.NewScrapeError(site, errors.ScrapeUpstreamChanged, err)
+}
+
+// UnknownHandleError is returned when the handle does not exist on the site
+func UnknownHandleError(site string) error {
+	return errors.NewScrapeError(site, errors.ScrapeHandleNotFound, fmt.Errorf("handle not found"))
+}
+
+// StatusError classifies a response with an unexpected status code
+func StatusError(site string, status int) error {
+	err := fmt.Errorf("unexpected status %d", status)
+	switch {
+	case status == http.StatusNotFound:
+		return errors.NewScrapeError(site, errors.ScrapeHandleNotFound, err)
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		return errors.NewScrapeError(site, errors.ScrapeRateLimited, err)
+	case status >= 500:
+		return errors.NewScrapeError(site, errors.ScrapeNetwork, err)
+	default:
+		return errors.NewScrapeError(site, errors.ScrapeUpstreamChanged, err)
+	}
+}
+
+// CollyError classifies the error of a colly request
+func CollyError(site string, resp *colly.Response, err error) error {
+	if resp == nil || resp.StatusCode == 0 {
+		return NetworkError(site, err)
+	}
+	return StatusError(site, resp.StatusCode)
+}
@@ -0,0 +1,122 @@
+package cses
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gocolly/colly"
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/scrappers/common"
+)
+
+// Address of cses, changed by tests to a local server
+var baseURL = "https://cses.fi"
+
+// Name of the site
+const CSES = "cses"
+
+// Handle of cses is the numeric ID of the user as CSES
+// identifies users by their ID in urls
+type Scrapper struct {
+	Handle  string
+	Context context.Context
+}
+
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:         CSES,
+		URLPrefix:    "https://cses.fi",
+		Capabilities: scrappers.Snapshot,
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return Scrapper{Handle: handle, Context: ctx}
+		},
+	})
+}
+
+func (s Scrapper) GetProfileInfo() (types.ProfileInfo, error) {
+	c := common.NewCollector()
+	var profile types.ProfileInfo
+
+	c.OnHTML(".content h1", func(e *colly.HTMLElement) {
+		// heading is of the form "User <username>"
+		profile.UserName = strings.TrimSpace(strings.TrimPrefix(e.Text, "User "))
+		profile.Name = profile.UserName
+	})
+
+	var scrapeErr error
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(CSES, resp, err)
+	})
+
+	err := c.Visit(baseURL + "/user/" + url.PathEscape(s.Handle))
+	if scrapeErr != nil {
+		return types.ProfileInfo{}, scrapeErr
+	}
+	if err != nil {
+		return types.ProfileInfo{}, common.NetworkError(CSES, err)
+	}
+	return profile, nil
+}
+
+// CSES doesn't expose submission times, so all solved tasks are returned
+// with the time of fetch irrespective of after.
+func (s Scrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
+	c := common.NewCollector()
+	var submissions []types.Submission
+	now := time.Now().UTC()
+
+	c.OnHTML("a.task-score.full", func(e *colly.HTMLElement) {
+		// Tasks are listed once, so the task id identifies the submission
+		submissions = append(submissions, types.Submission{
+			ID:           path.Base(e.Attr("href")),
+			Name:         e.Attr("title"),
+			URL:          baseURL + e.Attr("href"),
+			CreationDate: now,
+			Status:       StatusCorrect,
+			Points:       100,
+		})
+	})
+
+	var scrapeErr error
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(CSES, resp, err)
+	})
+
+	err := c.Visit(fmt.Sprintf("%s/problemset/user/%s/", baseURL, url.PathEscape(s.Handle)))
+	if scrapeErr != nil {
+		return nil, scrapeErr
+	}
+	if err != nil {
+		return nil, common.NetworkError(CSES, err)
+	}
+	return submissions, nil
+}
+
+func (s Scrapper) CheckHandle() (bool, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	resp, err := common.Client.Get(baseURL + "/user/" + url.PathEscape(s.Handle))
+	if err != nil {
+		hub.CaptureException(err)
+		return false, common.NetworkError(CSES, err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, common.StatusError(CSES, resp.StatusCode)
+	}
+}
@@ -0,0 +1,51 @@
+package cses
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/mdg-iitr/Codephile/conf"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/scrappers/scrappertest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapper(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/user/coder_101":             "user.html",
+		"/problemset/user/coder_101/": "problemset.html",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: CSES scrapper\n", t, func() {
+		Convey("Only fully solved tasks are returned", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 3)
+			So(subs[0].ID, ShouldEqual, "1068")
+			So(subs[0].Name, ShouldEqual, "Weird Algorithm")
+			So(subs[0].URL, ShouldEqual, server.URL+"/problemset/task/1068")
+			So(subs[0].Status, ShouldEqual, StatusCorrect)
+			So(subs[2].Name, ShouldEqual, "Distinct Numbers")
+		})
+		Convey("Profile is parsed", func() {
+			profile, err := s.GetProfileInfo()
+			So(err, ShouldBeNil)
+			So(profile.UserName, ShouldEqual, "coder_101")
+			valid, err := s.CheckHandle()
+			So(err, ShouldBeNil)
+			So(valid, ShouldBeTrue)
+			valid, err = Scrapper{Handle: "nosuchuser", Context: context.Background()}.CheckHandle()
+			So(err, ShouldBeNil)
+			So(valid, ShouldBeFalse)
+		})
+		Convey("Unknown handle is reported as not found", func() {
+			_, err := Scrapper{Handle: "nosuchuser", Context: context.Background()}.GetProfileInfo()
+			kind, _ := ScrapeErrorKindOf(err)
+			So(kind, ShouldEqual, ScrapeHandleNotFound)
+		})
+	})
+}
@@ -0,0 +1,184 @@
+package hackerearth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gocolly/colly"
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/scrappers/common"
+)
+
+// Address of hackerearth, changed by tests to a local server
+var baseURL = "https://www.hackerearth.com"
+
+// Name of the site
+const HACKEREARTH = "hackerearth"
+
+type Scrapper struct {
+	Handle  string
+	Context context.Context
+}
+
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:         HACKEREARTH,
+		URLPrefix:    "https://www.hackerearth.com",
+		Host:         "hackerearth.com",
+		Capabilities: scrappers.Verdicts,
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return Scrapper{Handle: handle, Context: ctx}
+		},
+	})
+}
+
+// layout of the submission time shown in the submissions table. The time
+// is shown to the minute without a zone, and is taken as UTC.
+const timeLayout = "Jan 2, 2006, 03:04 PM"
+
+func (s Scrapper) GetProfileInfo() (types.ProfileInfo, error) {
+	c := common.NewCollector()
+	profile := types.ProfileInfo{UserName: s.Handle}
+
+	c.OnHTML(".profile-card", func(e *colly.HTMLElement) {
+		profile.Name = strings.TrimSpace(e.ChildText(".name"))
+		profile.School = strings.TrimSpace(e.ChildText(".education .institute"))
+	})
+
+	var scrapeErr error
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(HACKEREARTH, resp, err)
+	})
+
+	err := c.Visit(fmt.Sprintf("%s/@%s/", baseURL, url.PathEscape(s.Handle)))
+	if scrapeErr != nil {
+		return types.ProfileInfo{}, scrapeErr
+	}
+	if err != nil {
+		return types.ProfileInfo{}, common.NetworkError(HACKEREARTH, err)
+	}
+	return profile, nil
+}
+
+func (s Scrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	var subs []types.Submission
+	//Fetch submission until oldest submission not found
+	for page := 1; ; page++ {
+		newSub, err := getSubmissionParts(s.Handle, page, hub)
+		if err != nil {
+			return nil, err
+		}
+		if len(newSub) == 0 {
+			break
+		}
+		for _, sub := range newSub {
+			// Submissions made in the minute of after may not be stored yet,
+			// the stored ones are skipped when they are added
+			if sub.CreationDate.Before(after) {
+				return subs, nil
+			}
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+func getSubmissionParts(handle string, page int, hub *sentry.Hub) ([]types.Submission, error) {
+	c := common.NewCollector()
+	var submissions []types.Submission
+	var scrapeErr error
+
+	c.OnHTML("table.submissions-table tbody", func(e *colly.HTMLElement) {
+		e.ForEach("tr", func(_ int, elem *colly.HTMLElement) {
+			name := elem.ChildText(".problem-name a")
+			URL := baseURL + elem.ChildAttr(".problem-name a", "href")
+			creationDate, err := time.Parse(timeLayout, elem.ChildAttr(".submission-time span", "title"))
+			if err != nil {
+				hub.CaptureException(err)
+				scrapeErr = common.ParseError(HACKEREARTH, err)
+				return
+			}
+			var status string
+			switch strings.ToLower(elem.ChildAttr(".result span", "title")) {
+			case "accepted":
+				status = StatusCorrect
+			case "partially accepted":
+				status = StatusPartial
+			case "compilation error":
+				status = StatusCompilationError
+			case "runtime error":
+				status = StatusRuntimeError
+			case "time limit exceeded":
+				status = StatusTimeLimitExceeded
+			case "memory limit exceeded":
+				status = StatusMemoryLimitExceeded
+			default:
+				status = StatusWrongAnswer
+			}
+			points := 0
+			if status == StatusCorrect {
+				points = 100
+			}
+			// Submission link is of the form /submission/<id>/
+			var id string
+			if href := elem.ChildAttr("a[href*='/submission/']", "href"); href != "" {
+				id = path.Base(strings.TrimSuffix(href, "/"))
+			}
+			submissions = append(submissions, types.Submission{
+				ID:           id,
+				Name:         name,
+				URL:          URL,
+				CreationDate: creationDate,
+				Status:       status,
+				Language:     elem.ChildText(".language"),
+				Points:       points,
+			})
+		})
+	})
+
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(HACKEREARTH, resp, err)
+	})
+
+	err := c.Visit(fmt.Sprintf("%s/submissions/%s/?page=%d", baseURL, url.PathEscape(handle), page))
+	if scrapeErr != nil {
+		return nil, scrapeErr
+	}
+	if err != nil {
+		return nil, common.NetworkError(HACKEREARTH, err)
+	}
+	return submissions, nil
+}
+
+func (s Scrapper) CheckHandle() (bool, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	resp, err := common.Client.Get(fmt.Sprintf("%s/@%s/", baseURL, url.PathEscape(s.Handle)))
+	if err != nil {
+		hub.CaptureException(err)
+		return false, common.NetworkError(HACKEREARTH, err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, common.StatusError(HACKEREARTH, resp.StatusCode)
+	}
+}
@@ -0,0 +1,80 @@
+package hackerearth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/scrappers/scrappertest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetSubmissions(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/submissions/coder_101/?page=1": "submissions_1.html",
+		"/submissions/coder_101/?page=2": "submissions_2.html",
+		"/submissions/coder_101/?page=3": "submissions_3.html",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: HackerEarth submissions\n", t, func() {
+		Convey("Pages are fetched till an empty page", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 14)
+			So(subs[0].ID, ShouldEqual, "35000000")
+			So(subs[0].Name, ShouldEqual, "Palindromic String")
+			So(subs[0].URL, ShouldEqual, server.URL+"/problem/algorithm/palindromic-string/")
+			So(subs[0].Language, ShouldEqual, "C++17")
+			So(subs[0].Points, ShouldEqual, 100)
+			So(subs[0].CreationDate, ShouldEqual, time.Date(2020, 1, 20, 18, 30, 0, 0, time.UTC))
+			So(subs[13].ID, ShouldEqual, "34997257")
+		})
+		Convey("Verdicts are mapped to the common statuses", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			var statuses []string
+			for _, sub := range subs[:7] {
+				statuses = append(statuses, sub.Status)
+			}
+			So(statuses, ShouldResemble, []string{
+				StatusCorrect, StatusWrongAnswer, StatusPartial, StatusCompilationError,
+				StatusRuntimeError, StatusTimeLimitExceeded, StatusMemoryLimitExceeded,
+			})
+		})
+		Convey("Submissions before after are left out", func() {
+			after := time.Date(2020, 1, 18, 11, 30, 0, 0, time.UTC)
+			subs, err := s.GetSubmissions(after)
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 12)
+			// Submissions of the same minute may not be stored yet
+			So(subs[11].CreationDate, ShouldEqual, after)
+		})
+	})
+}
+
+func TestProfile(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/@coder_101/": "profile.html",
+	})
+	defer server.Close()
+	baseURL = server.URL
+
+	Convey("Subject: HackerEarth profile\n", t, func() {
+		s := Scrapper{Handle: "coder_101", Context: context.Background()}
+		profile, err := s.GetProfileInfo()
+		So(err, ShouldBeNil)
+		So(profile.Name, ShouldEqual, "Coder One")
+		So(profile.UserName, ShouldEqual, "coder_101")
+		So(profile.School, ShouldEqual, "IIT Roorkee")
+		valid, err := s.CheckHandle()
+		So(err, ShouldBeNil)
+		So(valid, ShouldBeTrue)
+		valid, err = Scrapper{Handle: "nosuchuser", Context: context.Background()}.CheckHandle()
+		So(err, ShouldBeNil)
+		So(valid, ShouldBeFalse)
+	})
+}
@@ -3,90 +3,107 @@ package hackerrank
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"github.com/getsentry/sentry-go"
 	. "github.com/mdg-iitr/Codephile/conf"
 	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
 	"github.com/mdg-iitr/Codephile/scrappers/common"
-	"log"
 	"net/http"
+	"strings"
 	"time"
 )
 
+// Address of hackerrank, changed by tests to a local server
+var baseURL = "https://www.hackerrank.com"
+
+// Name of the site
+const HACKERRANK = "hackerrank"
+
 type Scrapper struct {
 	Handle string
 	Context context.Context
 }
 
-func (s Scrapper) GetProfileInfo() types.ProfileInfo {
-	hub := sentry.GetHubFromContext(s.Context)
-	if hub == nil {
-		hub = sentry.CurrentHub()
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:      HACKERRANK,
+		URLPrefix: "https://www.hackerrank.com",
+		Host:      "hackerrank.com",
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return Scrapper{Handle: handle, Context: ctx}
+		},
+	})
+}
+
+// Fetches the path, decoding the JSON response into v
+func fetch(path string, v interface{}, hub *sentry.Hub) error {
+	byteValue, statusCode, err := common.HitGetRequest(baseURL + path)
+	if err != nil {
+		return common.NetworkError(HACKERRANK, err)
 	}
-	path := "https://www.hackerrank.com/rest/contests/master/hackers/" + s.Handle + "/profile";
-	byteValue, _ := common.HitGetRequest(path)
-	if byteValue == nil {
-		err := errors.New("GetRequest failed. Please check connection status")
-		log.Println(err)
-		hub.CaptureException(err)
-		return types.ProfileInfo{}
+	if statusCode != http.StatusOK {
+		return common.StatusError(HACKERRANK, statusCode)
 	}
-	var JsonInterFace interface{}
-	err := json.Unmarshal(byteValue, &JsonInterFace)
+	err = json.Unmarshal(byteValue, v)
 	if err != nil {
 		hub.AddBreadcrumb(&sentry.Breadcrumb{
-			Category:  "JSON parse error",
-			Message:   string(byteValue),
+			Category: "JSON parse error",
+			Message:  string(byteValue),
 		}, nil)
 		hub.CaptureException(err)
-		log.Println(err.Error())
-		return types.ProfileInfo{}
+		return common.ParseError(HACKERRANK, err)
 	}
-	Profile := JsonInterFace.(map[string]interface{})["model"].(map[string]interface{})
-	Name := Profile["name"].(string)
-	// Date := Profile["created_at"].(string)
-	UserName := Profile["username"].(string)
-	School := Profile["school"].(string)
-	return types.ProfileInfo{Name: Name, UserName: UserName, School: School}
+	return nil
 }
 
-func (s Scrapper) GetSubmissions(after time.Time) []types.Submission {
+func (s Scrapper) GetProfileInfo() (types.ProfileInfo, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	var data struct {
+		Model struct {
+			Name     string `json:"name"`
+			Username string `json:"username"`
+			School   string `json:"school"`
+		} `json:"model"`
+	}
+	err := fetch("/rest/contests/master/hackers/"+s.Handle+"/profile", &data, hub)
+	if err != nil {
+		return types.ProfileInfo{}, err
+	}
+	// hackerrank only lists the solved challenges
+	return types.ProfileInfo{Name: data.Model.Name, UserName: data.Model.Username, School: data.Model.School, Accuracy: "1"}, nil
+}
+
+func (s Scrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
 	hub := sentry.GetHubFromContext(s.Context)
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
-	path := "https://www.hackerrank.com/rest/hackers/" + s.Handle + "/recent_challenges?limit=1000&response_version=v1"
-	byteValue, _ := common.HitGetRequest(path)
 	var data types.HackerrankSubmisson
-	err := json.Unmarshal(byteValue, &data)
-	submissions := data.Models
+	err := fetch("/rest/hackers/"+s.Handle+"/recent_challenges?limit=1000&response_version=v1", &data, hub)
 	if err != nil {
-		hub.AddBreadcrumb(&sentry.Breadcrumb{
-			Category:  "JSON parse error",
-			Message:   string(byteValue),
-		}, nil)
-		hub.CaptureException(err)
-		log.Println(err.Error())
-		return nil
+		return nil, err
 	}
-	var oldestSubIndex int;
-	if after.IsZero() {
-		oldestSubIndex = len(submissions)
-	} else {
-		for i, sub := range submissions {
-			if sub.CreationDate.Equal(after) || sub.CreationDate.Before(after) {
-				oldestSubIndex = i
-				break
-			}
+	submissions := data.Models
+	oldestSubIndex := len(submissions)
+	for i, sub := range submissions {
+		if sub.CreationDate.Equal(after) || sub.CreationDate.Before(after) {
+			oldestSubIndex = i
+			break
 		}
 	}
 	submissions = submissions[0:oldestSubIndex]
 	for i := 0; i < len(submissions); i++ {
 		submissions[i].Points = 100
 		submissions[i].Status = StatusCorrect
-		submissions[i].URL = "https://www.hackerrank.com" + submissions[i].URL
+		submissions[i].URL = baseURL + submissions[i].URL
+		// Challenges are listed once, so the challenge slug identifies the submission
+		slug := strings.TrimSuffix(submissions[i].URL, "/")
+		submissions[i].ID = slug[strings.LastIndex(slug, "/")+1:]
 	}
-	return submissions
+	return submissions, nil
 }
 
 func (s Scrapper) CheckHandle() (bool, error) {
@@ -94,11 +111,11 @@ func (s Scrapper) CheckHandle() (bool, error) {
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
-	resp, err := http.Get("https://www.hackerrank.com/rest/contests/master/hackers/" + s.Handle + "/profile")
+	resp, err := common.Client.Get(baseURL + "/rest/contests/master/hackers/" + s.Handle + "/profile")
 	if err != nil {
-		log.Println(err.Error())
 		hub.CaptureException(err)
-		return false, err
+		return false, common.NetworkError(HACKERRANK, err)
 	}
+	defer resp.Body.Close() // nolint: errcheck
 	return resp.StatusCode != http.StatusNotFound, nil
 }
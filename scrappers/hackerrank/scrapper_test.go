@@ -0,0 +1,70 @@
+package hackerrank
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/scrappers/scrappertest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetSubmissions(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/rest/hackers/coder_101/recent_challenges": "recent_challenges.json",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: Hackerrank submissions\n", t, func() {
+		Convey("Every listed challenge is a correct submission", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 4)
+			So(subs[0].ID, ShouldEqual, "ctci-array-left-rotation")
+			So(subs[0].Name, ShouldEqual, "Arrays: Left Rotation")
+			So(subs[0].URL, ShouldEqual, server.URL+"/challenges/ctci-array-left-rotation")
+			So(subs[0].CreationDate, ShouldEqual, time.Date(2020, 1, 20, 17, 36, 51, 0, time.UTC))
+			for _, sub := range subs {
+				So(sub.Status, ShouldEqual, StatusCorrect)
+				So(sub.Points, ShouldEqual, 100)
+			}
+		})
+		Convey("Challenges solved till after are left out", func() {
+			subs, err := s.GetSubmissions(time.Date(2020, 1, 15, 11, 2, 44, 0, time.UTC))
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 2)
+			So(subs[1].ID, ShouldEqual, "2d-array")
+		})
+		Convey("All challenges are returned when none is older than after", func() {
+			subs, err := s.GetSubmissions(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 4)
+		})
+	})
+}
+
+func TestProfile(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/rest/contests/master/hackers/coder_101/profile": "profile.json",
+	})
+	defer server.Close()
+	baseURL = server.URL
+
+	Convey("Subject: Hackerrank profile\n", t, func() {
+		s := Scrapper{Handle: "coder_101", Context: context.Background()}
+		profile, err := s.GetProfileInfo()
+		So(err, ShouldBeNil)
+		So(profile.Name, ShouldEqual, "Coder One")
+		So(profile.UserName, ShouldEqual, "coder_101")
+		So(profile.School, ShouldEqual, "Indian Institute of Technology Roorkee")
+		valid, err := s.CheckHandle()
+		So(err, ShouldBeNil)
+		So(valid, ShouldBeTrue)
+		valid, err = Scrapper{Handle: "nosuchuser", Context: context.Background()}.CheckHandle()
+		So(err, ShouldBeNil)
+		So(valid, ShouldBeFalse)
+	})
+}
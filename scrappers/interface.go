@@ -5,38 +5,60 @@ import (
 	"errors"
 	"time"
 
-	. "github.com/mdg-iitr/Codephile/conf"
 	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models/types"
-	"github.com/mdg-iitr/Codephile/scrappers/codechef"
-	"github.com/mdg-iitr/Codephile/scrappers/codeforces"
-	"github.com/mdg-iitr/Codephile/scrappers/hackerrank"
-	"github.com/mdg-iitr/Codephile/scrappers/spoj"
-	"github.com/mdg-iitr/Codephile/scrappers/leetcode"
 )
 
+// Scrapper fetches the data of a handle from a site. Errors returned while
+// fetching are *errors.ScrapeError, telling the cause of failure.
 type Scrapper interface {
 	CheckHandle() (bool, error)
-	GetSubmissions(after time.Time) []types.Submission
-	GetProfileInfo() types.ProfileInfo
+	GetSubmissions(after time.Time) ([]types.Submission, error)
+	GetProfileInfo() (types.ProfileInfo, error)
+}
+
+// RatingScrapper is implemented by the scrappers of sites
+// having rated contests
+type RatingScrapper interface {
+	GetRatingHistory() (types.RatingHistory, error)
+}
+
+// ContestScrapper is implemented by the scrappers of sites reporting
+// the performance of a user in the contests taken part in
+type ContestScrapper interface {
+	GetContests() ([]types.ContestParticipation, error)
+}
+
+// OwnershipScrapper is implemented by the scrappers of sites on which the
+// owner of a handle can prove the ownership by placing a token issued by
+// codephile in the profile of the handle
+type OwnershipScrapper interface {
+	// CheckOwnership reports whether the token of the challenge is placed
+	// in the profile, or a compilation error was submitted to the problem
+	// in the language of the challenge after it was issued
+	CheckOwnership(challenge types.OwnershipChallenge) (bool, error)
+	// OwnershipProblems returns the URLs of the problems, one of which is
+	// picked for every challenge, to which a compilation error can be
+	// submitted as the proof, nil if the site doesn't support it
+	OwnershipProblems() []string
+	// OwnershipLanguages returns the languages, one of which is picked for
+	// every challenge, in which the compilation error is to be submitted
+	OwnershipLanguages() []string
+}
+
+// HasRatingHistory reports whether the scrapper of the site implements RatingScrapper
+func (s Site) HasRatingHistory() bool {
+	_, ok := s.New("", context.Background()).(RatingScrapper)
+	return ok
 }
 
 func NewScrapper(site string, handle string, ctx context.Context) (Scrapper, error) {
 	if handle == "" {
 		return nil, HandleNotFoundError
 	}
-	switch site {
-	case CODEFORCES:
-		return codeforces.Scrapper{Handle: handle, Context: ctx}, nil
-	case CODECHEF:
-		return codechef.Scrapper{Handle: handle, Context: ctx}, nil
-	case HACKERRANK:
-		return hackerrank.Scrapper{Handle: handle, Context: ctx}, nil
-	case SPOJ:
-		return spoj.Scrapper{Handle: handle, Context: ctx}, nil
-	case LEETCODE:
-		return leetcode.Scrapper{Handle: handle, Context: ctx}, nil
-	default:
+	s, ok := Lookup(site)
+	if !ok {
 		return nil, errors.New("site invalid")
 	}
+	return s.New(handle, ctx), nil
 }
@@ -0,0 +1,134 @@
+package kattis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gocolly/colly"
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/scrappers/common"
+)
+
+// Address of kattis, changed by tests to a local server
+var baseURL = "https://open.kattis.com"
+
+// Name of the site
+const KATTIS = "kattis"
+
+type Scrapper struct {
+	Handle  string
+	Context context.Context
+}
+
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:         KATTIS,
+		URLPrefix:    "https://open.kattis.com",
+		Host:         "open.kattis.com",
+		Capabilities: scrappers.Snapshot,
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return Scrapper{Handle: handle, Context: ctx}
+		},
+	})
+}
+
+func (s Scrapper) GetProfileInfo() (types.ProfileInfo, error) {
+	c := common.NewCollector()
+	profile := types.ProfileInfo{UserName: s.Handle}
+
+	c.OnHTML("h1", func(e *colly.HTMLElement) {
+		if profile.Name == "" {
+			profile.Name = strings.TrimSpace(e.Text)
+		}
+	})
+	c.OnHTML(".divider_list-item", func(e *colly.HTMLElement) {
+		value := strings.TrimSpace(e.ChildText(".important_text"))
+		switch strings.TrimSpace(e.ChildText(".info_label")) {
+		case "Rank":
+			profile.WorldRank = value
+		case "University":
+			profile.School = value
+		}
+	})
+
+	var scrapeErr error
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(KATTIS, resp, err)
+	})
+
+	err := c.Visit(baseURL + "/users/" + url.PathEscape(s.Handle))
+	if scrapeErr != nil {
+		return types.ProfileInfo{}, scrapeErr
+	}
+	if err != nil {
+		return types.ProfileInfo{}, common.NetworkError(KATTIS, err)
+	}
+	return profile, nil
+}
+
+// Kattis only lists the problems solved by a user, so all of them are
+// returned with the time of fetch irrespective of after.
+func (s Scrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
+	c := common.NewCollector()
+	var submissions []types.Submission
+	now := time.Now().UTC()
+
+	c.OnHTML("table tbody tr", func(e *colly.HTMLElement) {
+		href := e.ChildAttr("a[href^='/problems/']", "href")
+		if href == "" {
+			return
+		}
+		// Problems are listed once, so the problem id identifies the submission
+		submissions = append(submissions, types.Submission{
+			ID:           path.Base(href),
+			Name:         e.ChildText("a[href^='/problems/']"),
+			URL:          baseURL + href,
+			CreationDate: now,
+			Status:       StatusCorrect,
+			Points:       100,
+		})
+	})
+
+	var scrapeErr error
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(KATTIS, resp, err)
+	})
+
+	err := c.Visit(fmt.Sprintf("%s/users/%s?tab=solved", baseURL, url.PathEscape(s.Handle)))
+	if scrapeErr != nil {
+		return nil, scrapeErr
+	}
+	if err != nil {
+		return nil, common.NetworkError(KATTIS, err)
+	}
+	return submissions, nil
+}
+
+func (s Scrapper) CheckHandle() (bool, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	resp, err := common.Client.Get(baseURL + "/users/" + url.PathEscape(s.Handle))
+	if err != nil {
+		hub.CaptureException(err)
+		return false, common.NetworkError(KATTIS, err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, common.StatusError(KATTIS, resp.StatusCode)
+	}
+}
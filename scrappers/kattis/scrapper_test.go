@@ -0,0 +1,47 @@
+package kattis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/scrappers/scrappertest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScrapper(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/users/coder_101?tab=solved": "solved.html",
+		"/users/coder_101":            "user.html",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: Kattis scrapper\n", t, func() {
+		Convey("Solved problems are returned", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 3)
+			So(subs[0].ID, ShouldEqual, "hello")
+			So(subs[0].Name, ShouldEqual, "Hello World!")
+			So(subs[0].URL, ShouldEqual, server.URL+"/problems/hello")
+			So(subs[0].Status, ShouldEqual, StatusCorrect)
+		})
+		Convey("Profile is parsed", func() {
+			profile, err := s.GetProfileInfo()
+			So(err, ShouldBeNil)
+			So(profile.Name, ShouldEqual, "Coder One")
+			So(profile.UserName, ShouldEqual, "coder_101")
+			So(profile.WorldRank, ShouldEqual, "2204")
+			So(profile.School, ShouldEqual, "Indian Institute of Technology Roorkee")
+			valid, err := s.CheckHandle()
+			So(err, ShouldBeNil)
+			So(valid, ShouldBeTrue)
+			valid, err = Scrapper{Handle: "nosuchuser", Context: context.Background()}.CheckHandle()
+			So(err, ShouldBeNil)
+			So(valid, ShouldBeFalse)
+		})
+	})
+}
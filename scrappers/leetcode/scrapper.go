@@ -4,51 +4,89 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	"github.com/mdg-iitr/Codephile/conf"
 	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/scrappers/common"
 )
 
+// Address of leetcode, changed by tests to a local server
+var baseURL = "https://leetcode.com"
+
+// Name of the site
+const LEETCODE = "leetcode"
+
 type Scrapper struct {
 	Handle  string
 	Context context.Context
 }
 
-func leetcodeGraphQLRequest(query string) ([]byte, error) {
-	jsonData := map[string]string{
-		"query": query,
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:      LEETCODE,
+		URLPrefix: "https://leetcode.com/",
+		Host:      "leetcode.com",
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return Scrapper{Handle: handle, Context: ctx}
+		},
+	})
+}
+
+// Makes the query, which takes the handle of the user as the $username
+// variable
+func leetcodeGraphQLRequest(query string, username string) ([]byte, error) {
+	jsonData := map[string]interface{}{
+		"query":     query,
+		"variables": map[string]string{"username": username},
 	}
 	jsonValue, err := json.Marshal(jsonData)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := http.Post("https://leetcode.com/graphql", "application/json", bytes.NewBuffer(jsonValue))
+	resp, err := common.Client.Post(baseURL+"/graphql", "application/json", bytes.NewBuffer(jsonValue))
 	if err != nil {
-		return nil, err
+		return nil, common.NetworkError(LEETCODE, err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, common.StatusError(LEETCODE, resp.StatusCode)
+	}
 	responseValue, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, common.NetworkError(LEETCODE, err)
 	}
 	return responseValue, err
 }
 
-func (s Scrapper) GetProfileInfo() types.ProfileInfo {
+// Reports the response which could not be parsed
+func parseError(hub *sentry.Hub, data []byte, err error) error {
+	hub.AddBreadcrumb(&sentry.Breadcrumb{
+		Category: "JSON parse error",
+		Message:  string(data),
+	}, nil)
+	hub.CaptureException(err)
+	return common.ParseError(LEETCODE, err)
+}
+
+func (s Scrapper) GetProfileInfo() (types.ProfileInfo, error) {
 	hub := sentry.GetHubFromContext(s.Context)
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
 
 	query := `
-		{
-			matchedUser(username: "` + s.Handle + `") {
+		query ($username: String!) {
+			matchedUser(username: $username) {
 				username
 				profile {
 					realName
@@ -66,22 +104,24 @@ func (s Scrapper) GetProfileInfo() types.ProfileInfo {
 			}
 		}
 	`
-	responseData, err := leetcodeGraphQLRequest(query)
+	responseData, err := leetcodeGraphQLRequest(query, s.Handle)
 	if err != nil {
-		log.Println(err.Error())
-		hub.CaptureException(err)
-		return types.ProfileInfo{}
+		return types.ProfileInfo{}, err
 	}
 	var responseValue types.GraphQLResponse
 	err = json.Unmarshal(responseData, &responseValue)
 	if err != nil {
-		log.Println(err.Error())
-		hub.CaptureException(err)
-		return types.ProfileInfo{}
+		return types.ProfileInfo{}, parseError(hub, responseData, err)
 	}
 	matchedUser := responseValue.Data.MatchedUser
+	if matchedUser.Username == "" {
+		return types.ProfileInfo{}, common.UnknownHandleError(LEETCODE)
+	}
 	profile := matchedUser.Profile
 	submitStats := matchedUser.SubmitStats
+	if len(submitStats.AcSubmissionNum) == 0 || len(submitStats.TotalSubmissionNum) == 0 {
+		return types.ProfileInfo{}, parseError(hub, responseData, errors.New("submission stats missing"))
+	}
 	accuracy := submitStats.AcSubmissionNum[0].Submissions / math.Max(1, submitStats.TotalSubmissionNum[0].Submissions) * 100
 	return types.ProfileInfo{
 		Name:      profile.RealName,
@@ -89,7 +129,7 @@ func (s Scrapper) GetProfileInfo() types.ProfileInfo {
 		School:    profile.School,
 		WorldRank: fmt.Sprintf("%.0f", profile.Ranking),
 		Accuracy:  fmt.Sprintf("%.2f", accuracy),
-	}
+	}, nil
 }
 
 func (s Scrapper) CheckHandle() (bool, error) {
@@ -99,67 +139,223 @@ func (s Scrapper) CheckHandle() (bool, error) {
 	}
 
 	query := `
-		{
-			matchedUser(username: "` + s.Handle + `") {
+		query ($username: String!) {
+			matchedUser(username: $username) {
 				username
 			}
 		}
 	`
-	responseData, err := leetcodeGraphQLRequest(query)
+	responseData, err := leetcodeGraphQLRequest(query, s.Handle)
 	if err != nil {
-		log.Println(err.Error())
-		hub.CaptureException(err)
 		return false, err
 	}
-	var responseValue map[string]interface{}
+	var responseValue types.GraphQLResponse
 	err = json.Unmarshal(responseData, &responseValue)
 	if err != nil {
-		log.Println(err.Error())
-		hub.CaptureException(err)
-		return false, err
+		return false, parseError(hub, responseData, err)
 	}
-	matchedUser := responseValue["data"].(map[string]interface{})["matchedUser"]
-	return matchedUser != nil, err
+	return responseValue.Data.MatchedUser.Username != "", nil
 }
 
-func (s Scrapper) GetSubmissions(after time.Time) []types.Submission {
+func (s Scrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
 	hub := sentry.GetHubFromContext(s.Context)
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
 
 	query := `
-            { 
-				recentSubmissionList(username: "` + s.Handle + `"){
+            query ($username: String!) {
+				recentSubmissionList(username: $username){
+					id
 					title
 					titleSlug
 				    timestamp
+					statusDisplay
+					lang
 				}	
             }`
 
-	body, err := leetcodeGraphQLRequest(query)
+	body, err := leetcodeGraphQLRequest(query, s.Handle)
+	if err != nil {
+		return nil, err
+	}
+	var response types.LeetcodeRecentSubmissions
+	err = json.Unmarshal(body, &response)
 	if err != nil {
-		hub.CaptureException(err)
-		log.Println(err.Error())
-		return nil
-	}
-	var Leetcodesubmissions []types.LeetcodeSubmissions
-	err1 := json.Unmarshal(body, &Leetcodesubmissions)
-	if err1 != nil {
-		hub.CaptureException(err1)
-		log.Println(err1.Error())
-		return nil
-	}
-	submissions := make([]types.Submission, len(Leetcodesubmissions))
-	for i, result := range Leetcodesubmissions {
-		submissions[i].Name = result.Title
-		submissions[i].URL = "https://leetcode.com/problems/" + result.URL
-		t, err := time.Parse("2006-01-02 15:04:05", result.TimeSTamp)
+		return nil, parseError(hub, body, err)
+	}
+	// List is null for unknown users
+	if response.Data.RecentSubmissionList == nil {
+		return nil, common.UnknownHandleError(LEETCODE)
+	}
+	var submissions []types.Submission
+	// Submissions are listed latest first
+	for _, result := range response.Data.RecentSubmissionList {
+		// timestamp is the unix time in seconds
+		seconds, err := strconv.ParseInt(result.TimeSTamp, 10, 64)
 		if err != nil {
-			hub.CaptureException(err)
+			return nil, parseError(hub, body, err)
+		}
+		creationDate := time.Unix(seconds, 0)
+		if !creationDate.After(after) {
+			break
+		}
+		var status string
+		switch result.Status {
+		case "Accepted":
+			status = conf.StatusCorrect
+		case "Compile Error":
+			status = conf.StatusCompilationError
+		case "Runtime Error":
+			status = conf.StatusRuntimeError
+		case "Time Limit Exceeded":
+			status = conf.StatusTimeLimitExceeded
+		case "Memory Limit Exceeded":
+			status = conf.StatusMemoryLimitExceeded
+		default:
+			status = conf.StatusWrongAnswer
+		}
+		points := 0
+		if status == conf.StatusCorrect {
+			points = 100
+		}
+		submissions = append(submissions, types.Submission{
+			ID:           result.ID,
+			Name:         result.Title,
+			URL:          baseURL + "/problems/" + result.URL,
+			CreationDate: creationDate,
+			Status:       status,
+			Language:     result.Language,
+			Points:       points,
+		})
+	}
+	return submissions, nil
+}
+
+// Returns the contests the user took part in, oldest first
+func fetchContestHistory(handle string, hub *sentry.Hub) ([]types.LeetcodeContestEntry, error) {
+	query := `
+		query ($username: String!) {
+			userContestRankingHistory(username: $username) {
+				attended
+				rating
+				ranking
+				problemsSolved
+				contest {
+					title
+					titleSlug
+					startTime
+				}
+			}
+		}
+	`
+	responseData, err := leetcodeGraphQLRequest(query, handle)
+	if err != nil {
+		return nil, err
+	}
+	var responseValue types.LeetcodeContestHistory
+	err = json.Unmarshal(responseData, &responseValue)
+	if err != nil {
+		return nil, parseError(hub, responseData, err)
+	}
+	var attended []types.LeetcodeContestEntry
+	for _, entry := range responseValue.Data.UserContestRankingHistory {
+		// Leetcode lists every contest, including the ones the user skipped
+		if entry.Attended {
+			attended = append(attended, entry)
+		}
+	}
+	return attended, nil
+}
+
+func (s Scrapper) GetRatingHistory() (types.RatingHistory, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	entries, err := fetchContestHistory(s.Handle, hub)
+	if err != nil {
+		return nil, err
+	}
+	history := make(types.RatingHistory, len(entries))
+	for i, entry := range entries {
+		history[i] = types.RatingChange{
+			Contest: entry.Contest.Title,
+			Rating:  int(math.Round(entry.Rating)),
+			Rank:    entry.Ranking,
+			Time:    time.Unix(entry.Contest.StartTime, 0),
+		}
+	}
+	return history, nil
+}
+
+func (s Scrapper) GetContests() ([]types.ContestParticipation, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	entries, err := fetchContestHistory(s.Handle, hub)
+	if err != nil {
+		return nil, err
+	}
+	contests := make([]types.ContestParticipation, len(entries))
+	// Every user starts with a rating of 1500
+	previous := 1500
+	for i, entry := range entries {
+		rating := int(math.Round(entry.Rating))
+		contests[i] = types.ContestParticipation{
+			Site:           LEETCODE,
+			ContestID:      entry.Contest.TitleSlug,
+			ContestName:    entry.Contest.Title,
+			Rank:           entry.Ranking,
+			Rating:         rating,
+			RatingChange:   rating - previous,
+			ProblemsSolved: entry.ProblemsSolved,
+			Time:           time.Unix(entry.Contest.StartTime, 0),
 		}
-		submissions[i].CreationDate = t
+		previous = rating
+	}
+	return contests, nil
+}
+
+func (s Scrapper) OwnershipProblems() []string {
+	return nil
+}
+
+func (s Scrapper) OwnershipLanguages() []string {
+	return nil
+}
 
+// CheckOwnership looks for the token in the name and summary of the user
+func (s Scrapper) CheckOwnership(challenge types.OwnershipChallenge) (bool, error) {
+	hub := sentry.GetHubFromContext(s.Context)
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	query := `
+		query ($username: String!) {
+			matchedUser(username: $username) {
+				username
+				profile {
+					realName
+					aboutMe
+				}
+			}
+		}
+	`
+	responseData, err := leetcodeGraphQLRequest(query, s.Handle)
+	if err != nil {
+		return false, err
 	}
-	return submissions
+	var responseValue types.GraphQLResponse
+	err = json.Unmarshal(responseData, &responseValue)
+	if err != nil {
+		return false, parseError(hub, responseData, err)
+	}
+	matchedUser := responseValue.Data.MatchedUser
+	if matchedUser.Username == "" {
+		return false, common.UnknownHandleError(LEETCODE)
+	}
+	profile := matchedUser.Profile
+	return strings.Contains(profile.RealName+" "+profile.AboutMe, challenge.Token), nil
 }
@@ -0,0 +1,133 @@
+package leetcode
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/scrappers/scrappertest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Every query is made to the same endpoint, so each test serves one fixture
+func serve(t *testing.T, fixture string) func() {
+	server := scrappertest.NewServer(t, map[string]string{"/graphql": fixture})
+	baseURL = server.URL
+	return server.Close
+}
+
+func TestGetSubmissions(t *testing.T) {
+	defer serve(t, "recent_submissions.json")()
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: Leetcode submissions\n", t, func() {
+		Convey("Submission list is parsed", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 6)
+			So(subs[0].ID, ShouldEqual, "512338841")
+			So(subs[0].Name, ShouldEqual, "Two Sum")
+			So(subs[0].URL, ShouldEqual, baseURL+"/problems/two-sum")
+			So(subs[0].Language, ShouldEqual, "cpp")
+			So(subs[0].Points, ShouldEqual, 100)
+			So(subs[0].CreationDate, ShouldEqual, time.Unix(1629450000, 0))
+		})
+		Convey("Verdicts are mapped to the common statuses", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			var statuses []string
+			for _, sub := range subs {
+				statuses = append(statuses, sub.Status)
+			}
+			So(statuses, ShouldResemble, []string{
+				StatusCorrect, StatusWrongAnswer, StatusTimeLimitExceeded,
+				StatusRuntimeError, StatusCompilationError, StatusMemoryLimitExceeded,
+			})
+		})
+		Convey("Submissions till after are left out", func() {
+			subs, err := s.GetSubmissions(time.Unix(1629369500, 0))
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 3)
+			So(subs[2].ID, ShouldEqual, "511962003")
+		})
+	})
+}
+
+func TestProfile(t *testing.T) {
+	Convey("Subject: Leetcode profile\n", t, func() {
+		Convey("Profile is parsed", func() {
+			defer serve(t, "profile.json")()
+			s := Scrapper{Handle: "coder_101", Context: context.Background()}
+			profile, err := s.GetProfileInfo()
+			So(err, ShouldBeNil)
+			So(profile.Name, ShouldEqual, "Coder One")
+			So(profile.UserName, ShouldEqual, "coder_101")
+			So(profile.School, ShouldEqual, "IIT Roorkee")
+			So(profile.WorldRank, ShouldEqual, "104532")
+			So(profile.Accuracy, ShouldEqual, "37.50")
+			valid, err := s.CheckHandle()
+			So(err, ShouldBeNil)
+			So(valid, ShouldBeTrue)
+		})
+		Convey("Unknown handle is invalid", func() {
+			defer serve(t, "no_user.json")()
+			valid, err := Scrapper{Handle: "nosuchuser", Context: context.Background()}.CheckHandle()
+			So(err, ShouldBeNil)
+			So(valid, ShouldBeFalse)
+		})
+	})
+}
+
+func TestGetContests(t *testing.T) {
+	defer serve(t, "contest_history.json")()
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: Leetcode contests\n", t, func() {
+		Convey("Skipped contests are left out", func() {
+			history, err := s.GetRatingHistory()
+			So(err, ShouldBeNil)
+			So(history, ShouldHaveLength, 2)
+			So(history[0].Rating, ShouldEqual, 1523)
+			So(history[1].Contest, ShouldEqual, "Weekly Contest 251")
+		})
+		Convey("Rating change starts from 1500", func() {
+			contests, err := s.GetContests()
+			So(err, ShouldBeNil)
+			So(contests, ShouldHaveLength, 2)
+			So(contests[0].ContestID, ShouldEqual, "weekly-contest-250")
+			So(contests[0].RatingChange, ShouldEqual, 23)
+			So(contests[0].ProblemsSolved, ShouldEqual, 2)
+			So(contests[1].RatingChange, ShouldEqual, -24)
+		})
+	})
+}
+
+func TestHandleSentAsVariable(t *testing.T) {
+	var body struct {
+		Query     string            `json:"query"`
+		Variables map[string]string `json:"variables"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		w.Write([]byte(`{"data":{"matchedUser":null}}`))
+	}))
+	defer server.Close()
+	baseURL = server.URL
+	handle := `x") { username } }`
+	s := Scrapper{Handle: handle, Context: context.Background()}
+
+	Convey("Subject: Leetcode query variables\n", t, func() {
+		Convey("Handle is passed in the variables, not in the query", func() {
+			_, err := s.CheckHandle()
+			So(err, ShouldBeNil)
+			So(body.Variables["username"], ShouldEqual, handle)
+			So(body.Query, ShouldNotContainSubstring, handle)
+		})
+	})
+}
@@ -0,0 +1,104 @@
+package scrappers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mdg-iitr/Codephile/models/types"
+)
+
+// Capability describes an optional feature of a site
+type Capability uint
+
+const (
+	// Verdicts is set for sites which report every submission along with
+	// its verdict. Accuracy is calculated only for such sites.
+	Verdicts Capability = 1 << iota
+	// Snapshot is set for sites which only list the problems solved by
+	// a user, without the time of submission. Such scrappers return every
+	// solved problem stamped with the time of fetch and the problems
+	// already stored are skipped. So the time based views, like the
+	// activity graph and the feed, show the problems as solved when they
+	// were first fetched; all the problems solved before the handle was
+	// added show up on the day of the first fetch.
+	Snapshot
+)
+
+// Site describes a platform supported by codephile. Every scrapper package
+// registers its site from an init function.
+type Site struct {
+	// Name of the site. Also used as the key of the handle, lastfetched
+	// and profile of the site in the user document
+	Name string
+	// Prefix of the url of every submission made on the site
+	URLPrefix string
+	// Host of the site as listed by clist, empty if site has no contests
+	Host         string
+	Capabilities Capability
+	// Returns a scrapper for the given handle
+	New func(handle string, ctx context.Context) Scrapper
+}
+
+func (s Site) Has(c Capability) bool {
+	return s.Capabilities&c == c
+}
+
+var (
+	sitesMu sync.RWMutex
+	sites   = map[string]Site{}
+)
+
+// Register makes a site available to the rest of codephile.
+// Panics if a site with same name is registered twice.
+func Register(site Site) {
+	sitesMu.Lock()
+	defer sitesMu.Unlock()
+	if site.Name == "" || site.New == nil {
+		panic("scrappers: Register called with incomplete site")
+	}
+	if _, dup := sites[site.Name]; dup {
+		panic("scrappers: Register called twice for site " + site.Name)
+	}
+	sites[site.Name] = site
+	types.RegisterSiteName(site.Name)
+}
+
+// Lookup returns the registered site with given name
+func Lookup(name string) (Site, bool) {
+	sitesMu.RLock()
+	defer sitesMu.RUnlock()
+	site, ok := sites[name]
+	return site, ok
+}
+
+// Sites returns all the registered sites sorted by name
+func Sites() []Site {
+	sitesMu.RLock()
+	defer sitesMu.RUnlock()
+	list := make([]Site, 0, len(sites))
+	for _, site := range sites {
+		list = append(list, site)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func IsSiteValid(name string) bool {
+	_, ok := Lookup(name)
+	return ok
+}
+
+// SiteFromURL returns the name of the site the url belongs to
+func SiteFromURL(url string) (string, error) {
+	for _, site := range Sites() {
+		if strings.HasPrefix(url, site.URLPrefix) || (site.Host != "" && strings.Contains(url, site.Host)) {
+			return site.Name, nil
+		}
+	}
+	return url, fmt.Errorf("unrecognised platform URL: %s", url)
+}
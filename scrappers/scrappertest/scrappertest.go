@@ -0,0 +1,50 @@
+// Package scrappertest replays recorded responses of the platforms, so
+// that the scrappers can be tested without network
+package scrappertest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/astaxie/beego"
+)
+
+var limitOnce sync.Once
+
+// NewServer starts a server responding to the routes with the files in
+// testdata. A route is either a path or a path with its query, the latter
+// taking precedence. Requests to any other route are answered with 404.
+func NewServer(t *testing.T, routes map[string]string) *httptest.Server {
+	// Fixtures are served locally, so they needn't be rate limited
+	limitOnce.Do(func() {
+		_ = beego.AppConfig.Set("SCRAPPER_RATE_127.0.0.1", "10000")
+		_ = beego.AppConfig.Set("SCRAPPER_BURST_127.0.0.1", "10000")
+	})
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, ok := routes[r.URL.RequestURI()]
+		if !ok {
+			file, ok = routes[r.URL.Path]
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := ioutil.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Errorf("reading fixture %s: %v", file, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		switch filepath.Ext(file) {
+		case ".json":
+			w.Header().Set("Content-Type", "application/json")
+		case ".html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		_, _ = w.Write(data)
+	}))
+}
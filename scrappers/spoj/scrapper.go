@@ -1,32 +1,51 @@
 package spoj
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"github.com/getsentry/sentry-go"
 	"github.com/gocolly/colly"
 	. "github.com/mdg-iitr/Codephile/conf"
+	. "github.com/mdg-iitr/Codephile/errors"
 	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/scrappers/common"
 	"log"
-	"regexp"
 	"strings"
 	"time"
 )
 
+// Address of spoj, changed by tests to a local server
+var baseURL = "https://www.spoj.com"
+
+// Name of the site
+const SPOJ = "spoj"
+
 type Scrapper struct {
 	Handle  string
 	Context context.Context
 }
 
-func (s Scrapper) GetProfileInfo() types.ProfileInfo {
-	hub := sentry.GetHubFromContext(s.Context)
-	if hub == nil {
-		hub = sentry.CurrentHub()
-	}
-	c := colly.NewCollector()
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:         SPOJ,
+		URLPrefix:    "https://www.spoj.com",
+		Host:         "spoj.com",
+		Capabilities: scrappers.Verdicts,
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return Scrapper{Handle: handle, Context: ctx}
+		},
+	})
+}
+
+func (s Scrapper) GetProfileInfo() (types.ProfileInfo, error) {
+	c := common.NewCollector()
 	var Profile types.ProfileInfo
+	var found bool
 
 	c.OnHTML("#user-profile-left", func(e *colly.HTMLElement) {
+		found = true
 		Name := e.ChildText("h3")
 		flag := 0
 		var WorldRank string
@@ -55,30 +74,35 @@ func (s Scrapper) GetProfileInfo() types.ProfileInfo {
 		Profile = types.ProfileInfo{Name: Name, UserName: UserName, School: School, WorldRank: WorldRank}
 	})
 
-	c.OnError(func(_ *colly.Response, err error) {
-		fmt.Println("Something went wrong:", err)
-		hub.CaptureException(err)
+	var scrapeErr error
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(SPOJ, resp, err)
 	})
 
-	err := c.Visit(fmt.Sprintf("https://www.spoj.com/users/%s/", s.Handle))
+	err := c.Visit(fmt.Sprintf("%s/users/%s/", baseURL, s.Handle))
+	if scrapeErr != nil {
+		return types.ProfileInfo{}, scrapeErr
+	}
 	if err != nil {
-		hub.CaptureException(err)
-		log.Println(err.Error())
+		return types.ProfileInfo{}, common.NetworkError(SPOJ, err)
 	}
-	return Profile
+	// Profile page of unknown users redirects to the home page
+	if !found {
+		return types.ProfileInfo{}, common.UnknownHandleError(SPOJ)
+	}
+	return Profile, nil
 }
 
-func (s Scrapper) GetSubmissions(after time.Time) []types.Submission {
-	hub := sentry.GetHubFromContext(s.Context)
-	if hub == nil {
-		hub = sentry.CurrentHub()
-	}
+func (s Scrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
 	var oldestSubIndex, current int
 	var oldestSubFound = false
 	subs := []types.Submission{{CreationDate: time.Now()}}
 	//Fetch submission until oldest submission not found
 	for !oldestSubFound {
-		newSub := getSubmissionParts(s.Handle, current, hub)
+		newSub, err := getSubmissionParts(s.Handle, current)
+		if err != nil {
+			return nil, err
+		}
 		//Check for repetition of previous fetched submission
 		if len(newSub) != 0 && newSub[0].CreationDate.Before(subs[len(subs)-1].CreationDate) {
 			for i, sub := range newSub {
@@ -97,21 +121,21 @@ func (s Scrapper) GetSubmissions(after time.Time) []types.Submission {
 		}
 	}
 	if len(subs) < 2 {
-		return nil
+		return nil, nil
 	}
 	subs = subs[1 : oldestSubIndex+1]
-	return subs
+	return subs, nil
 }
 
-func getSubmissionParts(handle string, afterIndex int, hub *sentry.Hub) []types.Submission {
+func getSubmissionParts(handle string, afterIndex int) ([]types.Submission, error) {
 
-	c := colly.NewCollector()
+	c := common.NewCollector()
 	var submissions []types.Submission
 
 	c.OnHTML("tbody", func(e *colly.HTMLElement) {
 		e.ForEach("tr", func(_ int, elem *colly.HTMLElement) {
 			Name := elem.ChildText(".sproblem a")
-			URL := "https://www.spoj.com" + elem.ChildAttr(".sproblem a", "href")
+			URL := baseURL + elem.ChildAttr(".sproblem a", "href")
 			str_date := elem.ChildText(".status_sm span")
 			CreationDate, err := time.Parse("2006-01-02 15:04:05", str_date)
 			if err != nil {
@@ -137,25 +161,25 @@ func getSubmissionParts(handle string, afterIndex int, hub *sentry.Hub) []types.
 				points = 100
 			}
 			tags := getProbTags(URL)
-			submissions = append(submissions, types.Submission{Name: Name, URL: URL, CreationDate: CreationDate, Status: status, Language: language, Points: points, Tags: tags})
+			// Result cell has the id statusres_<submission id>
+			ID := strings.TrimPrefix(elem.ChildAttr(".statusres", "id"), "statusres_")
+			submissions = append(submissions, types.Submission{ID: ID, Name: Name, URL: URL, CreationDate: CreationDate, Status: status, Language: language, Points: points, Tags: tags})
 		})
 	})
 
-	c.OnError(func(_ *colly.Response, err error) {
-		hub.CaptureException(err)
-		fmt.Println("Something went wrong:", err)
+	var scrapeErr error
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(SPOJ, resp, err)
 	})
 
-	c.OnRequest(func(request *colly.Request) {
-		fmt.Println(request.URL)
-	})
-	err := c.Visit(fmt.Sprintf("https://www.spoj.com/status/%s/all/start=%d", handle, afterIndex))
+	err := c.Visit(fmt.Sprintf("%s/status/%s/all/start=%d", baseURL, handle, afterIndex))
+	if scrapeErr != nil {
+		return nil, scrapeErr
+	}
 	if err != nil {
-		hub.CaptureException(err)
-		log.Println(err.Error())
+		return nil, common.NetworkError(SPOJ, err)
 	}
-
-	return submissions
+	return submissions, nil
 }
 
 func (s Scrapper) CheckHandle() (bool, error) {
@@ -163,26 +187,32 @@ func (s Scrapper) CheckHandle() (bool, error) {
 	if hub == nil {
 		hub = sentry.CurrentHub()
 	}
-	c := colly.NewCollector()
+	c := common.NewCollector()
 	var valid = false
-	var err error
+	var scrapeErr error
 	c.OnResponse(func(response *colly.Response) {
-		valid, err = regexp.Match("user-profile-left", response.Body)
-		if err != nil {
-			hub.CaptureException(err)
-		}
+		valid = bytes.Contains(response.Body, []byte("user-profile-left"))
 	})
-	err = c.Visit(fmt.Sprintf("https://www.spoj.com/users/%s/", s.Handle))
+	c.OnError(func(resp *colly.Response, err error) {
+		scrapeErr = common.CollyError(SPOJ, resp, err)
+	})
+	err := c.Visit(fmt.Sprintf("%s/users/%s/", baseURL, s.Handle))
+	if scrapeErr != nil {
+		if kind, _ := ScrapeErrorKindOf(scrapeErr); kind == ScrapeHandleNotFound {
+			return false, nil
+		}
+		return false, scrapeErr
+	}
 	if err != nil {
-		log.Println(err.Error())
 		hub.CaptureException(err)
+		return false, common.NetworkError(SPOJ, err)
 	}
 	return valid, nil
 }
 
 func getProbTags(url string) []string {
 	var tags []string
-	c := colly.NewCollector()
+	c := common.NewCollector()
 	c.OnHTML(".problem-tag", func(e *colly.HTMLElement) {
 		tags = append(tags, e.Text)
 	})
@@ -0,0 +1,82 @@
+package spoj
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/mdg-iitr/Codephile/conf"
+	"github.com/mdg-iitr/Codephile/scrappers/scrappertest"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetSubmissions(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/status/coder_101/all/start=0":  "status_0.html",
+		"/status/coder_101/all/start=20": "status_20.html",
+		"/status/coder_101/all/start=40": "status_40.html",
+		"/problems/TEST/":                "problem_TEST.html",
+		"/problems/PRIME1/":              "problem_PRIME1.html",
+		"/problems/ONP/":                 "problem_ONP.html",
+	})
+	defer server.Close()
+	baseURL = server.URL
+	s := Scrapper{Handle: "coder_101", Context: context.Background()}
+
+	Convey("Subject: SPOJ submissions\n", t, func() {
+		Convey("Status pages are fetched till an empty page", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 23)
+			So(subs[0].ID, ShouldEqual, "25000000")
+			So(subs[0].Name, ShouldEqual, "TEST")
+			So(subs[0].URL, ShouldEqual, server.URL+"/problems/TEST/")
+			So(subs[0].Language, ShouldEqual, "C++")
+			So(subs[0].Points, ShouldEqual, 100)
+			So(subs[0].CreationDate, ShouldEqual, time.Date(2020, 1, 28, 10, 15, 42, 0, time.UTC))
+			So(subs[1].Tags, ShouldResemble, []string{"#number-theory", "#sieve"})
+			So(subs[22].ID, ShouldEqual, "24999186")
+		})
+		Convey("Verdicts are mapped to the common statuses", func() {
+			subs, err := s.GetSubmissions(time.Time{})
+			So(err, ShouldBeNil)
+			var statuses []string
+			for _, sub := range subs[:5] {
+				statuses = append(statuses, sub.Status)
+			}
+			So(statuses, ShouldResemble, []string{
+				StatusCorrect, StatusWrongAnswer, StatusCompilationError, StatusRuntimeError, StatusWrongAnswer,
+			})
+		})
+		Convey("Submissions till after are left out", func() {
+			subs, err := s.GetSubmissions(time.Date(2020, 1, 7, 9, 0, 0, 0, time.UTC))
+			So(err, ShouldBeNil)
+			So(subs, ShouldHaveLength, 21)
+			So(subs[20].CreationDate, ShouldEqual, time.Date(2020, 1, 8, 9, 0, 0, 0, time.UTC))
+		})
+	})
+}
+
+func TestProfile(t *testing.T) {
+	server := scrappertest.NewServer(t, map[string]string{
+		"/users/coder_101/": "profile.html",
+	})
+	defer server.Close()
+	baseURL = server.URL
+
+	Convey("Subject: SPOJ profile\n", t, func() {
+		s := Scrapper{Handle: "coder_101", Context: context.Background()}
+		profile, err := s.GetProfileInfo()
+		So(err, ShouldBeNil)
+		So(profile.Name, ShouldEqual, "Coder One")
+		So(profile.UserName, ShouldEqual, "coder_101")
+		So(strings.TrimSpace(profile.WorldRank), ShouldEqual, "#10403 (45.6 points)")
+		So(strings.TrimSpace(profile.School), ShouldEqual, "IIT Roorkee")
+		valid, err := s.CheckHandle()
+		So(err, ShouldBeNil)
+		So(valid, ShouldBeTrue)
+		valid, _ = Scrapper{Handle: "nosuchuser", Context: context.Background()}.CheckHandle()
+		So(valid, ShouldBeFalse)
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,14 +19,29 @@ import (
 // to be used if a user is suspicious
 var UserBlacklisted = "blacklisted"
 
-func GenerateToken(uid string) string {
+// Seconds for which an access token is valid
+var accessTokenTTL = beego.AppConfig.DefaultInt64("ACCESS_TOKEN_DURATION", 900)
+
+// Claims of the access tokens
+type Claims struct {
+	jwt.StandardClaims
+	// Space separated scopes granted to the token
+	Scope string `json:"scope"`
+}
+
+// GenerateToken issues a short lived access token of the session, granted
+// the given scopes
+func GenerateToken(uid string, sessionID string, scopes []string) string {
 	currentTimestamp := time.Now().UTC().Unix()
-	var ttl = beego.AppConfig.DefaultInt64("TOKENDURATION", 3600000)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		ExpiresAt: currentTimestamp + ttl,
-		IssuedAt:  currentTimestamp,
-		Issuer:    "mdg",
-		Subject:   uid,
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: currentTimestamp + accessTokenTTL,
+			IssuedAt:  currentTimestamp,
+			Issuer:    "mdg",
+			Subject:   uid,
+			Id:        sessionID,
+		},
+		Scope: strings.Join(scopes, " "),
 	})
 	tokenString, err := token.SignedString([]byte(os.Getenv("HMACKEY")))
 	if err != nil {
@@ -34,6 +50,9 @@ func GenerateToken(uid string) string {
 	}
 	return tokenString
 }
+
+// BlacklistToken revokes a token issued before the sessions, which
+// carries no session id
 func BlacklistToken(token *jwt.Token) error {
 	client := redis.GetRedisClient()
 	claims := token.Claims.(jwt.MapClaims)
@@ -44,14 +63,25 @@ func BlacklistToken(token *jwt.Token) error {
 	}
 	return err
 }
+
+// IsTokenBlacklisted reports whether the token is revoked, either with its
+// session or with the user
 func IsTokenBlacklisted(token *jwt.Token) bool {
 	client := redis.GetRedisClient()
 	claims := token.Claims.(jwt.MapClaims)
 	val, err := client.Get(claims["sub"].(string)).Result()
+	if err != nil && err != r.Nil {
+		return true
+	}
+	if val == UserBlacklisted {
+		return true
+	}
+	if sessionID := SessionID(token); sessionID != "" {
+		n, err := client.Exists(sessionKey(sessionID)).Result()
+		return err != nil || n == 0
+	}
 	if err == r.Nil {
 		return false
-	} else if err != nil {
-		return true
 	}
 	iat, _ := strconv.ParseInt(val, 10, 64)
 	if int64(claims["iat"].(float64)) == iat || val == UserBlacklisted {
@@ -68,25 +98,58 @@ func getTokenRemainingValidity(timestamp interface{}) time.Duration {
 			return remained
 		}
 	}
-	return 0;
+	return 0
 }
+
 func IsTokenExpired(token *jwt.Token) bool {
 	exp := int64(token.Claims.(jwt.MapClaims)["exp"].(float64))
 	return exp <= time.Now().UTC().Unix()
 }
 
+// Scopes granted to the tokens issued before the scopes. They can only
+// read, logging in again gets a token with the scopes of the user.
+var legacyScopes = []string{"read:user", "read:follow", "read:feed", "read:submission", "read:contests"}
+
+// TokenScopes returns the scopes granted to the token
+func TokenScopes(token *jwt.Token) []string {
+	scope, ok := token.Claims.(jwt.MapClaims)["scope"].(string)
+	if !ok {
+		return legacyScopes
+	}
+	return strings.Fields(scope)
+}
+
+// SessionID returns the id of the session the token belongs to, empty for
+// the tokens issued before the sessions
+func SessionID(token *jwt.Token) string {
+	jti, _ := token.Claims.(jwt.MapClaims)["jti"].(string)
+	return jti
+}
+
+// IsUserBlacklisted reports whether the user is barred from logging in
+func IsUserBlacklisted(uid bson.ObjectId) bool {
+	val, err := redis.GetRedisClient().Get(uid.Hex()).Result()
+	if err == r.Nil {
+		return false
+	}
+	return err != nil || val == UserBlacklisted
+}
+
 func BlacklistUser(uid bson.ObjectId) error {
 	client := redis.GetRedisClient()
-	_, err := client.Set(string(uid), UserBlacklisted, 0).Result()
-	return err
+	_, err := client.Set(uid.Hex(), UserBlacklisted, 0).Result()
+	if err != nil {
+		return err
+	}
+	return RevokeAllSessions(uid.Hex())
 }
 
 func WhitelistUser(uid bson.ObjectId) error {
 	client := redis.GetRedisClient()
-	val := client.Get(string(uid)).Val()
+	val := client.Get(uid.Hex()).Val()
 	if val != UserBlacklisted {
 		return errors.New("already whitelisted")
 	}
-	_, err := client.Del(string(uid)).Result()
+	_, err := client.Del(uid.Hex()).Result()
 	return err
 }
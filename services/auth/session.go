@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/astaxie/beego"
+	r "github.com/go-redis/redis"
+	"github.com/google/uuid"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/services/redis"
+)
+
+// Time for which a session can be refreshed since the last refresh
+var refreshTokenTTL = time.Duration(beego.AppConfig.DefaultInt64("REFRESH_TOKEN_DURATION", 2419200)) * time.Second
+
+// Session of a user logged in on a device. The access tokens of the
+// session carry its id as jti and stop working once it is revoked.
+type Session struct {
+	ID          string    `json:"id"`
+	UserAgent   string    `json:"user_agent"`
+	IP          string    `json:"ip"`
+	CreatedAt   time.Time `json:"created_at"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	Current     bool      `json:"current"`
+}
+
+// Tokens issued to a session
+type Tokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// Seconds for which the access token is valid
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+// Session as stored in redis
+type storedSession struct {
+	Session
+	UID string `json:"uid"`
+	// Hash of the secret of the latest refresh token
+	RefreshHash string   `json:"refresh_hash"`
+	Scopes      []string `json:"scopes"`
+}
+
+func sessionKey(sessionID string) string {
+	return "session:" + sessionID
+}
+
+// Set of the ids of the sessions of a user
+func userSessionsKey(uid string) string {
+	return "sessions:" + uid
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Stores the session with a new refresh secret and issues its tokens.
+// The session is stored with a transaction of the client, which fails
+// with TxFailedErr if the client watches a key changed meanwhile.
+func issueTokens(client r.Cmdable, s storedSession) (Tokens, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return Tokens{}, err
+	}
+	s.RefreshHash = hashSecret(secret)
+	s.Current = false
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return Tokens{}, err
+	}
+	_, err = client.TxPipelined(func(pipe r.Pipeliner) error {
+		pipe.Set(sessionKey(s.ID), raw, refreshTokenTTL)
+		pipe.SAdd(userSessionsKey(s.UID), s.ID)
+		pipe.Expire(userSessionsKey(s.UID), refreshTokenTTL)
+		return nil
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{
+		AccessToken:  GenerateToken(s.UID, s.ID, s.Scopes),
+		RefreshToken: s.ID + "." + secret,
+		ExpiresIn:    accessTokenTTL,
+	}, nil
+}
+
+// CreateSession logs the user in on a new device. The tokens of the
+// session are granted the given scopes.
+func CreateSession(uid string, userAgent string, ip string, scopes []string) (Tokens, error) {
+	now := time.Now().UTC()
+	return issueTokens(redis.GetRedisClient(), storedSession{
+		Session: Session{
+			ID:          uuid.New().String(),
+			UserAgent:   userAgent,
+			IP:          ip,
+			CreatedAt:   now,
+			RefreshedAt: now,
+		},
+		UID:    uid,
+		Scopes: scopes,
+	})
+}
+
+// RefreshSession issues new tokens for the session of the refresh token,
+// granted the scopes the user holds now so that a change of role takes
+// effect on the next refresh. A refresh token can be used only once; using
+// it again revokes the session, as it might have been stolen. Of the
+// concurrent refreshes with the same token, only the first succeeds.
+// Returns InvalidRefreshTokenError if the token or its session is invalid
+func RefreshSession(refreshToken string, scopes func(uid string) []string) (Tokens, error) {
+	parts := strings.SplitN(refreshToken, ".", 2)
+	if len(parts) != 2 {
+		return Tokens{}, InvalidRefreshTokenError
+	}
+	var tokens Tokens
+	reused := false
+	// The session is watched so that the refresh token is swapped only if
+	// it wasn't swapped since it was compared
+	err := redis.GetRedisClient().Watch(func(tx *r.Tx) error {
+		s, err := readSession(tx, parts[0])
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(hashSecret(parts[1])), []byte(s.RefreshHash)) != 1 {
+			reused = true
+			return nil
+		}
+		s.RefreshedAt = time.Now().UTC()
+		s.Scopes = scopes(s.UID)
+		tokens, err = issueTokens(tx, s)
+		return err
+	}, sessionKey(parts[0]))
+	if err == r.TxFailedErr {
+		// Refreshed meanwhile with the same token
+		reused = true
+	} else if err == SessionNotFoundError {
+		return Tokens{}, InvalidRefreshTokenError
+	} else if err != nil {
+		return Tokens{}, err
+	}
+	if reused {
+		return Tokens{}, revokeReused(parts[0])
+	}
+	return tokens, nil
+}
+
+// Revokes the session whose refresh token is reused, as the token might
+// have been stolen. Returns InvalidRefreshTokenError once revoked.
+func revokeReused(sessionID string) error {
+	s, err := getSession(sessionID)
+	if err == nil {
+		err = RevokeSession(s.UID, s.ID)
+	}
+	if err != nil && err != SessionNotFoundError {
+		return err
+	}
+	return InvalidRefreshTokenError
+}
+
+func getSession(sessionID string) (storedSession, error) {
+	return readSession(redis.GetRedisClient(), sessionID)
+}
+
+func readSession(client r.Cmdable, sessionID string) (storedSession, error) {
+	raw, err := client.Get(sessionKey(sessionID)).Bytes()
+	if err == r.Nil {
+		return storedSession{}, SessionNotFoundError
+	} else if err != nil {
+		return storedSession{}, err
+	}
+	var s storedSession
+	err = json.Unmarshal(raw, &s)
+	return s, err
+}
+
+// GetSessions returns the active sessions of the user, latest refreshed
+// first. The session with the given id is marked current.
+func GetSessions(uid string, currentID string) ([]Session, error) {
+	client := redis.GetRedisClient()
+	ids, err := client.SMembers(userSessionsKey(uid)).Result()
+	if err != nil {
+		return nil, err
+	}
+	sessions := []Session{}
+	for _, id := range ids {
+		s, err := getSession(id)
+		if err == SessionNotFoundError {
+			// Expired sessions are dropped lazily
+			client.SRem(userSessionsKey(uid), id)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		s.Current = s.ID == currentID
+		sessions = append(sessions, s.Session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].RefreshedAt.After(sessions[j].RefreshedAt)
+	})
+	return sessions, nil
+}
+
+// RevokeSession logs the user out of the session, invalidating its access
+// and refresh tokens.
+// Returns SessionNotFoundError if the user has no such session
+func RevokeSession(uid string, sessionID string) error {
+	s, err := getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if s.UID != uid {
+		return SessionNotFoundError
+	}
+	client := redis.GetRedisClient()
+	pipe := client.TxPipeline()
+	pipe.Del(sessionKey(sessionID))
+	pipe.SRem(userSessionsKey(uid), sessionID)
+	_, err = pipe.Exec()
+	return err
+}
+
+// RevokeAllSessions logs the user out of every session
+func RevokeAllSessions(uid string) error {
+	client := redis.GetRedisClient()
+	ids, err := client.SMembers(userSessionsKey(uid)).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{userSessionsKey(uid)}
+	for _, id := range ids {
+		keys = append(keys, sessionKey(id))
+	}
+	return client.Del(keys...).Err()
+}
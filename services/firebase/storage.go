@@ -94,3 +94,22 @@ func DeleteObject(key string) error {
 	bucket, _ := client.DefaultBucket()
 	return bucket.Object(key).Delete(context.Background())
 }
+
+// DeletePicture deletes the profile picture with the given url. The
+// default pictures, shared by many users, and the pictures stored
+// elsewhere are left alone.
+func DeletePicture(picture string) error {
+	parts := strings.SplitN(picture, "/profile/", 2)
+	if len(parts) != 2 || picture != URLFromName(parts[1]) {
+		return nil
+	}
+	for _, pic := range beego.AppConfig.DefaultStrings("DEFAULT_PICS", []string{}) {
+		if parts[1] == pic {
+			return nil
+		}
+	}
+	if client == nil {
+		return errors.New("firebase conf not available")
+	}
+	return DeleteObject("profile/" + parts[1])
+}
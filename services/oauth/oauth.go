@@ -0,0 +1,245 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	r "github.com/go-redis/redis"
+	"github.com/google/uuid"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/services/redis"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Time within which the user has to complete the login on the provider
+const StateTTL = 10 * time.Minute
+
+// Identity of the user on a provider
+type Identity struct {
+	ID            string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	// Username on the provider, if any
+	Login string
+}
+
+// Provider is an OAuth2 identity provider users can log in with
+type Provider struct {
+	Name     string
+	endpoint oauth2.Endpoint
+	scopes   []string
+	// Fetches the identity of the user the client is authorized for
+	identity func(client *http.Client) (Identity, error)
+}
+
+var providers = map[string]Provider{
+	"google": {
+		Name:     "google",
+		endpoint: google.Endpoint,
+		scopes:   []string{"openid", "email", "profile"},
+		identity: googleIdentity,
+	},
+	"github": {
+		Name: "github",
+		endpoint: oauth2.Endpoint{
+			AuthURL:  "https://github.com/login/oauth/authorize",
+			TokenURL: "https://github.com/login/oauth/access_token",
+		},
+		scopes:   []string{"read:user", "user:email"},
+		identity: githubIdentity,
+	},
+}
+
+// Lookup returns the provider with the given name, if it is configured.
+// No provider is available unless the client url is configured too.
+func Lookup(name string) (Provider, bool) {
+	p, ok := providers[name]
+	if !ok || p.clientID() == "" || ClientURL() == "" {
+		return Provider{}, false
+	}
+	return p, true
+}
+
+// ClientURL returns the url of the client to which the user is redirected
+// at the end of the login, read from OAUTH_CLIENT_URL
+func ClientURL() string {
+	return os.Getenv("OAUTH_CLIENT_URL")
+}
+
+// Client credentials are read from <NAME>_CLIENT_ID and <NAME>_CLIENT_SECRET
+func (p Provider) clientID() string {
+	return os.Getenv(strings.ToUpper(p.Name) + "_CLIENT_ID")
+}
+
+func (p Provider) clientSecret() string {
+	return os.Getenv(strings.ToUpper(p.Name) + "_CLIENT_SECRET")
+}
+
+func (p Provider) config(redirectURL string) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.clientID(),
+		ClientSecret: p.clientSecret(),
+		Endpoint:     p.endpoint,
+		RedirectURL:  redirectURL,
+		Scopes:       p.scopes,
+	}
+}
+
+// AuthCodeURL returns the url of the login page of the provider
+func (p Provider) AuthCodeURL(redirectURL string, state string) string {
+	return p.config(redirectURL).AuthCodeURL(state)
+}
+
+// Exchange trades the code given by the provider after the login for the
+// identity of the user
+func (p Provider) Exchange(ctx context.Context, redirectURL string, code string) (Identity, error) {
+	config := p.config(redirectURL)
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		return Identity{}, err
+	}
+	return p.identity(config.Client(ctx, token))
+}
+
+// Login in progress on a provider
+type state struct {
+	Provider string `json:"provider"`
+	// User linking the provider, empty when logging in
+	UID string `json:"uid,omitempty"`
+	// Hash of the secret kept by the browser starting the login
+	BindingHash string `json:"binding_hash"`
+}
+
+func stateKey(s string) string {
+	return "oauth_state:" + s
+}
+
+func hashBinding(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewState creates the state to be passed through the login on the
+// provider, protecting the callback from forged requests. uid is of the
+// user linking the provider, empty when logging in. The returned binding
+// is kept by the browser starting the login and must be presented along
+// with the state, so that a login started by someone else can't be
+// completed in the browser.
+func NewState(provider string, uid string) (string, string, error) {
+	s := uuid.New().String()
+	binding := uuid.New().String()
+	raw, err := json.Marshal(state{Provider: provider, UID: uid, BindingHash: hashBinding(binding)})
+	if err != nil {
+		return "", "", err
+	}
+	err = redis.GetRedisClient().Set(stateKey(s), raw, StateTTL).Err()
+	return s, binding, err
+}
+
+// ConsumeState checks the state returned by the provider, which can be
+// used only once, against the binding presented by the browser. Returns
+// the uid of the user linking the provider, if any.
+// Returns OAuthStateInvalidError if the state is not issued for the
+// provider and the browser
+func ConsumeState(provider string, s string, binding string) (string, error) {
+	client := redis.GetRedisClient()
+	raw, err := client.Get(stateKey(s)).Bytes()
+	if err == r.Nil {
+		return "", OAuthStateInvalidError
+	} else if err != nil {
+		return "", err
+	}
+	if err = client.Del(stateKey(s)).Err(); err != nil {
+		return "", err
+	}
+	var st state
+	if err = json.Unmarshal(raw, &st); err != nil {
+		return "", err
+	}
+	if st.Provider != provider ||
+		subtle.ConstantTimeCompare([]byte(st.BindingHash), []byte(hashBinding(binding))) != 1 {
+		return "", OAuthStateInvalidError
+	}
+	return st.UID, nil
+}
+
+func getJSON(client *http.Client, url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("identity request failed with status " + resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func googleIdentity(client *http.Client) (Identity, error) {
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	err := getJSON(client, "https://openidconnect.googleapis.com/v1/userinfo", &info)
+	if err != nil {
+		return Identity{}, err
+	}
+	return Identity{
+		ID:            info.Sub,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		Name:          info.Name,
+		Picture:       info.Picture,
+	}, nil
+}
+
+func githubIdentity(client *http.Client) (Identity, error) {
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	err := getJSON(client, "https://api.github.com/user", &user)
+	if err != nil {
+		return Identity{}, err
+	}
+	identity := Identity{
+		ID:      strconv.FormatInt(user.ID, 10),
+		Name:    user.Name,
+		Picture: user.AvatarURL,
+		Login:   user.Login,
+	}
+	// The public email of the profile might be unverified or missing
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	err = getJSON(client, "https://api.github.com/user/emails", &emails)
+	if err != nil {
+		return Identity{}, err
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+		}
+	}
+	return identity, nil
+}
@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/getsentry/sentry-go"
+	goredis "github.com/go-redis/redis"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/services/redis"
+	"github.com/mdg-iitr/Codephile/services/worker"
+)
+
+// Redis keys used by the scheduler. State is kept in redis so that it is
+// shared by all the running instances.
+const (
+	pausedKey = "scheduler:paused"
+	// Held by the instance scheduling the current round
+	lockKey = "scheduler:lock"
+	// Hash of the time of last round and the jobs enqueued per site in it
+	statsKey = "scheduler:stats"
+)
+
+var (
+	interval         time.Duration
+	activeInterval   time.Duration
+	inactiveInterval time.Duration
+	activeWindow     time.Duration
+	defaultBudget    int
+	startOnce        sync.Once
+)
+
+func init() {
+	interval = seconds("SCHEDULER_INTERVAL", 60)
+	activeInterval = seconds("REFRESH_INTERVAL_ACTIVE", 6*60*60)
+	inactiveInterval = seconds("REFRESH_INTERVAL_INACTIVE", 48*60*60)
+	activeWindow = seconds("ACTIVE_WINDOW", 7*24*60*60)
+	defaultBudget = beego.AppConfig.DefaultInt("SCHEDULER_BUDGET", 10)
+}
+
+func seconds(key string, def int) time.Duration {
+	return time.Duration(beego.AppConfig.DefaultInt(key, def)) * time.Second
+}
+
+// Number of jobs of the site enqueued in a round, configured by
+// SCHEDULER_BUDGET_<site> and defaulting to SCHEDULER_BUDGET
+func budget(site string) int {
+	return beego.AppConfig.DefaultInt("SCHEDULER_BUDGET_"+site, defaultBudget)
+}
+
+// Status of the scheduler, as shown to admins
+type Status struct {
+	Paused   bool              `json:"paused"`
+	LastRun  time.Time         `json:"last_run"`
+	Enqueued map[string]int    `json:"enqueued"`
+	Budgets  map[string]int    `json:"budgets"`
+	Queue    worker.QueueStats `json:"queue"`
+}
+
+// Start periodically enqueues refresh jobs of the users whose data is
+// stale. Calling it again has no effect.
+func Start() {
+	startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				schedule()
+			}
+		}()
+	})
+}
+
+func schedule() {
+	client := redis.GetRedisClient()
+	paused, err := client.Exists(pausedKey).Result()
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	if paused == 1 {
+		return
+	}
+	// Only one instance schedules a round
+	locked, err := client.SetNX(lockKey, 1, interval-time.Second).Result()
+	if err != nil || !locked {
+		return
+	}
+	stats := map[string]interface{}{"last_run": time.Now().UTC().Unix()}
+	activeSince := time.Now().Add(-activeWindow)
+	for _, site := range scrappers.Sites() {
+		uids, err := models.GetStaleUsers(site.Name, activeSince, activeInterval, inactiveInterval, budget(site.Name))
+		if err != nil {
+			sentry.CaptureException(err)
+			log.Println(err.Error())
+			continue
+		}
+		var enqueued int
+		for _, uid := range uids {
+			// Fails if the job is already queued or the queue is full,
+			// the user is picked again in a later round
+			if err := worker.Enqueue(worker.NewJob(uid, site.Name, models.RefreshSite)); err == nil {
+				enqueued++
+				if err = models.MarkScheduled(uid, site.Name); err != nil {
+					log.Println(err.Error())
+				}
+			}
+		}
+		stats["enqueued:"+site.Name] = enqueued
+	}
+	if err := client.HMSet(statsKey, stats).Err(); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// Pause stops scheduling of new jobs. Jobs already in queue are still performed.
+func Pause() error {
+	return redis.GetRedisClient().Set(pausedKey, 1, 0).Err()
+}
+
+func Resume() error {
+	return redis.GetRedisClient().Del(pausedKey).Err()
+}
+
+func GetStatus() (Status, error) {
+	client := redis.GetRedisClient()
+	paused, err := client.Exists(pausedKey).Result()
+	if err != nil {
+		return Status{}, err
+	}
+	stats, err := client.HGetAll(statsKey).Result()
+	if err != nil && err != goredis.Nil {
+		return Status{}, err
+	}
+	status := Status{
+		Paused:   paused == 1,
+		Enqueued: map[string]int{},
+		Budgets:  map[string]int{},
+	}
+	if lastRun, err := strconv.ParseInt(stats["last_run"], 10, 64); err == nil {
+		status.LastRun = time.Unix(lastRun, 0).UTC()
+	}
+	for _, site := range scrappers.Sites() {
+		status.Enqueued[site.Name], _ = strconv.Atoi(stats["enqueued:"+site.Name])
+		status.Budgets[site.Name] = budget(site.Name)
+	}
+	status.Queue, err = worker.Stats()
+	return status, err
+}
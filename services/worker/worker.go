@@ -2,76 +2,573 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"log"
+	"math"
+	"reflect"
+	"runtime"
 	"sync"
+	"time"
 
 	"github.com/astaxie/beego"
+	"github.com/getsentry/sentry-go"
 	"github.com/globalsign/mgo/bson"
+	goredis "github.com/go-redis/redis"
+	"github.com/google/uuid"
 	"github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/services/redis"
 )
 
 type User bson.ObjectId
 type Website string
 
+type Handler func(user bson.ObjectId, website string, ctx context.Context) error
+
 type Job struct {
 	user        User
 	websiteName Website
 	//handler func which is called when job is performed
-	handler func(user bson.ObjectId, website string, ctx context.Context) error
+	handler Handler
+}
+
+// Job as stored in redis. Handler is stored by its name, so it must be
+// registered before the job is picked up.
+type jobData struct {
+	ID       string        `json:"id"`
+	User     bson.ObjectId `json:"user"`
+	Website  string        `json:"website"`
+	Handler  string        `json:"handler"`
+	Attempts int           `json:"attempts"`
+}
+
+// Job which failed on every attempt
+type deadJob struct {
+	jobData
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+// Redis keys used by the queue
+const (
+	// Hash of job id to job data
+	jobsKey = "worker:jobs"
+	// List of ids of jobs waiting to be picked up
+	pendingKey = "worker:pending"
+	// Sorted set of ids of jobs being performed, scored by the time
+	// after which the job is considered abandoned
+	processingKey = "worker:processing"
+	// Sorted set of ids of failed jobs, scored by the time of next attempt
+	delayedKey = "worker:delayed"
+	// Set of keys of the jobs in queue, used to reject duplicate jobs
+	activeKey = "worker:active"
+	// Hash of job id to the key of the job, so that the key can be
+	// removed from the active set even when the job data is lost
+	jobKeysKey = "worker:keys"
+	// List of jobs which failed on every attempt, latest first
+	deadKey = "worker:dead"
+)
+
+const maxDeadJobs = 1000
+
+// Pops a pending job and marks it as being processed till ARGV[1]
+var dequeueScript = goredis.NewScript(`
+local id = redis.call('RPOP', KEYS[1])
+if id then
+	redis.call('ZADD', KEYS[2], ARGV[1], id)
+end
+return id
+`)
+
+// Adds the job ARGV[2] with data ARGV[3] and key ARGV[1] unless a job with
+// the same key is in queue or ARGV[4] jobs are waiting already. Returns 1
+// on success, 0 if the queue is full and -1 if the job is already queued.
+var enqueueScript = goredis.NewScript(`
+if redis.call('LLEN', KEYS[4]) + redis.call('ZCARD', KEYS[5]) >= tonumber(ARGV[4]) then
+	return 0
+end
+if redis.call('SADD', KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+redis.call('HSET', KEYS[2], ARGV[2], ARGV[3])
+redis.call('HSET', KEYS[3], ARGV[2], ARGV[1])
+redis.call('LPUSH', KEYS[4], ARGV[2])
+return 1
+`)
+
+// Removes every trace of the job ARGV[1], freeing its key for new jobs
+var removeScript = goredis.NewScript(`
+local key = redis.call('HGET', KEYS[3], ARGV[1])
+if key then
+	redis.call('SREM', KEYS[1], key)
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('ZREM', KEYS[4], ARGV[1])
+return 1
+`)
+
+// Removes the job from the queue along with its key
+func remove(client goredis.Cmdable, id string) error {
+	return removeScript.Run(client, []string{activeKey, jobsKey, jobKeysKey, processingKey}, id).Err()
+}
+
+var (
+	handlersMu sync.RWMutex
+	handlers   = map[string]Handler{}
+	reporter   Reporter
+)
+
+// Reporter is told the result of every attempt of a job. retrying is set
+// when the job failed and will be attempted again.
+type Reporter func(user bson.ObjectId, website string, err error, retrying bool)
+
+var (
+	maxQueueSize      int64
+	maxAttempts       int
+	visibilityTimeout time.Duration
+	retryBackoff      time.Duration
+	pollInterval      = time.Second
+	startOnce         sync.Once
+)
+
+func init() {
+	maxQueueSize = beego.AppConfig.DefaultInt64("MAX_QUEUE_SIZE", 100)
+	maxAttempts = beego.AppConfig.DefaultInt("JOB_MAX_ATTEMPTS", 5)
+	visibilityTimeout = time.Duration(beego.AppConfig.DefaultInt("JOB_VISIBILITY_TIMEOUT", 300)) * time.Second
+	retryBackoff = time.Duration(beego.AppConfig.DefaultInt("JOB_RETRY_BACKOFF", 30)) * time.Second
+}
+
+func handlerName(handler Handler) string {
+	return runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+}
+
+// RegisterHandler makes the handler available to the workers. Jobs
+// surviving a restart are only performed once their handler is registered.
+func RegisterHandler(handler Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[handlerName(handler)] = handler
+}
+
+// SetReporter sets the function to which the results of jobs are reported
+func SetReporter(r Reporter) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	reporter = r
 }
 
-// Keeps track if a job corresponding to a user is already in the queue
-type UserQueue struct {
-	m map[User]bool
-	sync.Mutex
+func report(data jobData, err error, retrying bool) {
+	handlersMu.RLock()
+	r := reporter
+	handlersMu.RUnlock()
+	if r != nil {
+		r(data.User, data.Website, err, retrying)
+	}
 }
 
-var jobQueue chan Job
-var userQueue UserQueue
+func lookupHandler(name string) (Handler, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	handler, ok := handlers[name]
+	return handler, ok
+}
 
 func NewJob(user bson.ObjectId, websiteName string, handler func(user bson.ObjectId, website string, ctx context.Context) error) Job {
+	RegisterHandler(handler)
 	return Job{user: User(user), websiteName: Website(websiteName), handler: handler}
 }
 
-func init() {
-	jobQueue = make(chan Job, beego.AppConfig.DefaultInt("MAX_QUEUE_SIZE", 100))
-	userQueue = UserQueue{m: map[User]bool{}}
-	startWorkerCoRoutines()
+// Identifies the job among the ones in queue
+func (job Job) key() string {
+	return handlerName(job.handler) + ":" + bson.ObjectId(job.user).Hex() + ":" + string(job.websiteName)
+}
+
+func (data jobData) key() string {
+	return data.Handler + ":" + data.User.Hex() + ":" + data.Website
+}
+
+// Start starts the worker co-routines and the co-routine requeueing
+// delayed and abandoned jobs. Calling it again has no effect.
+func Start() {
+	startOnce.Do(func() {
+		for i := 0; i < beego.AppConfig.DefaultInt("MAX_WORKER_POOL", 1); i++ {
+			go work()
+		}
+		go requeue()
+	})
 }
 
 func work() {
-	for job := range jobQueue {
-		err := job.handler(bson.ObjectId(job.user), string(job.websiteName), context.Background())
+	client := redis.GetRedisClient()
+	for {
+		deadline := time.Now().Add(visibilityTimeout).Unix()
+		id, err := dequeueScript.Run(client, []string{pendingKey, processingKey}, deadline).String()
+		if err == goredis.Nil {
+			time.Sleep(pollInterval)
+			continue
+		} else if err != nil {
+			log.Println(err.Error())
+			time.Sleep(pollInterval)
+			continue
+		}
+		perform(client, id)
+	}
+}
+
+func perform(client *goredis.Client, id string) {
+	raw, err := client.HGet(jobsKey, id).Result()
+	var data jobData
+	if err == nil {
+		err = json.Unmarshal([]byte(raw), &data)
+	}
+	if err != nil {
+		// Job data is lost, nothing can be done
+		log.Println("worker: dropping job", id, err)
+		if err = remove(client, id); err != nil {
+			log.Println(err.Error())
+		}
+		return
+	}
+	handler, ok := lookupHandler(data.Handler)
+	if !ok {
+		fail(client, data, fmt.Errorf("handler %s not registered", data.Handler))
+		return
+	}
+
+	done := make(chan struct{})
+	go heartbeat(client, id, done)
+	err = run(handler, data)
+	close(done)
+	if err != nil {
+		log.Println("unable to fetch submissions/profile", err.Error())
+		fail(client, data, err)
+		return
+	}
+	report(data, nil, false)
+	if err = remove(client, id); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// Runs the handler, converting a panic into an error
+func run(handler Handler, data jobData) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			sentry.CurrentHub().Recover(r)
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(data.User, data.Website, context.Background())
+}
+
+// Extends the visibility timeout of the job till done is closed
+func heartbeat(client *goredis.Client, id string, done chan struct{}) {
+	ticker := time.NewTicker(visibilityTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			client.ZAddXX(processingKey, goredis.Z{
+				Score:  float64(time.Now().Add(visibilityTimeout).Unix()),
+				Member: id,
+			})
+		}
+	}
+}
+
+// Schedules the job to be retried with exponential backoff, or moves it
+// to the dead letter list once all attempts are used up or the handle is
+// not found.
+// Caller must own the job i.e. it must not be in any list or set.
+func fail(client *goredis.Client, data jobData, cause error) {
+	data.Attempts++
+	// Retrying can't help when the handle doesn't exist on the site
+	kind, _ := errors.ScrapeErrorKindOf(cause)
+	if data.Attempts >= maxAttempts || kind == errors.ScrapeHandleNotFound {
+		report(data, cause, false)
+		dead, _ := json.Marshal(deadJob{jobData: data, Error: cause.Error(), FailedAt: time.Now().UTC()})
+		_, err := client.TxPipelined(func(pipe goredis.Pipeliner) error {
+			pipe.ZRem(processingKey, data.ID)
+			pipe.HDel(jobsKey, data.ID)
+			pipe.HDel(jobKeysKey, data.ID)
+			pipe.SRem(activeKey, data.key())
+			pipe.LPush(deadKey, dead)
+			pipe.LTrim(deadKey, 0, maxDeadJobs-1)
+			return nil
+		})
 		if err != nil {
-			log.Println("unable to fetch submissions/profile", err.Error())
+			log.Println(err.Error())
 		}
-		userQueue.Lock()
-		delete(userQueue.m, job.user)
-		userQueue.Unlock()
+		return
+	}
+	report(data, cause, true)
+	backoff := time.Duration(float64(retryBackoff) * math.Pow(2, float64(data.Attempts-1)))
+	if backoff > time.Hour {
+		backoff = time.Hour
+	}
+	raw, _ := json.Marshal(data)
+	_, err := client.TxPipelined(func(pipe goredis.Pipeliner) error {
+		pipe.HSet(jobsKey, data.ID, raw)
+		pipe.ZRem(processingKey, data.ID)
+		pipe.ZAdd(delayedKey, goredis.Z{Score: float64(time.Now().Add(backoff).Unix()), Member: data.ID})
+		return nil
+	})
+	if err != nil {
+		log.Println(err.Error())
 	}
 }
 
-func startWorkerCoRoutines() {
-	for i := 0; i < beego.AppConfig.DefaultInt("MAX_WORKER_POOL", 1); i++ {
-		go work()
+// Periodically moves the delayed jobs which are due back to the pending
+// list, and retries the jobs whose worker stopped responding
+func requeue() {
+	client := redis.GetRedisClient()
+	for {
+		now := fmt.Sprint(time.Now().Unix())
+		due, err := client.ZRangeByScore(delayedKey, goredis.ZRangeBy{Min: "-inf", Max: now}).Result()
+		if err != nil {
+			log.Println(err.Error())
+		}
+		for _, id := range due {
+			// Removal claims the job, only one instance requeues it
+			if n, _ := client.ZRem(delayedKey, id).Result(); n == 1 {
+				client.LPush(pendingKey, id)
+			}
+		}
+		abandoned, err := client.ZRangeByScore(processingKey, goredis.ZRangeBy{Min: "-inf", Max: now}).Result()
+		if err != nil {
+			log.Println(err.Error())
+		}
+		for _, id := range abandoned {
+			if n, _ := client.ZRem(processingKey, id).Result(); n != 1 {
+				continue
+			}
+			raw, err := client.HGet(jobsKey, id).Result()
+			var data jobData
+			if err == nil {
+				err = json.Unmarshal([]byte(raw), &data)
+			}
+			if err != nil {
+				log.Println("worker: dropping job", id, err)
+				if err = remove(client, id); err != nil {
+					log.Println(err.Error())
+				}
+				continue
+			}
+			fail(client, data, fmt.Errorf("visibility timeout expired"))
+		}
+		time.Sleep(pollInterval * 5)
 	}
 }
 
+// Enqueue adds the job to the queue. Delayed jobs are counted towards the
+// size of the queue too, as they are still to be performed. Returns
+// ErrJobAlreadyQueued if the same job is already present.
 func Enqueue(job Job) error {
-	// Don't allow if a job corresponding to a user is already present
-	if _, ok := userQueue.m[job.user]; ok {
+	data := jobData{
+		ID:      uuid.New().String(),
+		User:    bson.ObjectId(job.user),
+		Website: string(job.websiteName),
+		Handler: handlerName(job.handler),
+	}
+	raw, _ := json.Marshal(data)
+	keys := []string{activeKey, jobsKey, jobKeysKey, pendingKey, delayedKey}
+	added, err := enqueueScript.Run(redis.GetRedisClient(), keys, job.key(), data.ID, raw, maxQueueSize).Int64()
+	if err != nil {
+		return err
+	}
+	switch added {
+	case 0:
 		return errors.ErrJobQueueFull
+	case -1:
+		return errors.ErrJobAlreadyQueued
 	}
-	select {
-	case jobQueue <- job:
-		userQueue.Lock()
-		defer userQueue.Unlock()
-		//jobQueue <- job
-		userQueue.m[job.user] = true
+	return nil
+}
+
+// IsQueued tells if a job of the user for the website is waiting to be
+// performed or retried
+func IsQueued(user bson.ObjectId, website string) (bool, error) {
+	handlersMu.RLock()
+	keys := make([]string, 0, len(handlers))
+	for name := range handlers {
+		keys = append(keys, name+":"+user.Hex()+":"+website)
+	}
+	handlersMu.RUnlock()
+	if len(keys) == 0 {
+		return false, nil
+	}
+	client := redis.GetRedisClient()
+	cmds := make([]*goredis.BoolCmd, len(keys))
+	_, err := client.Pipelined(func(pipe goredis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.SIsMember(activeKey, key)
+		}
 		return nil
-	//Check if queue is full
-	default:
-		return errors.ErrJobQueueFull
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, cmd := range cmds {
+		if cmd.Val() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Number of jobs in each state of the queue
+type QueueStats struct {
+	Pending    int64 `json:"pending"`
+	Processing int64 `json:"processing"`
+	Delayed    int64 `json:"delayed"`
+	Dead       int64 `json:"dead"`
+}
+
+func Stats() (QueueStats, error) {
+	client := redis.GetRedisClient()
+	var (
+		pending, dead       *goredis.IntCmd
+		processing, delayed *goredis.IntCmd
+	)
+	_, err := client.Pipelined(func(pipe goredis.Pipeliner) error {
+		pending = pipe.LLen(pendingKey)
+		processing = pipe.ZCard(processingKey)
+		delayed = pipe.ZCard(delayedKey)
+		dead = pipe.LLen(deadKey)
+		return nil
+	})
+	if err != nil {
+		return QueueStats{}, err
+	}
+	return QueueStats{
+		Pending:    pending.Val(),
+		Processing: processing.Val(),
+		Delayed:    delayed.Val(),
+		Dead:       dead.Val(),
+	}, nil
+}
+
+// QueuedJob is a job in queue as shown to admins
+type QueuedJob struct {
+	ID       string        `json:"id"`
+	User     bson.ObjectId `json:"user"`
+	Website  string        `json:"website"`
+	Handler  string        `json:"handler"`
+	Attempts int           `json:"attempts"`
+	// Time of the next attempt of a delayed job
+	RetryAt time.Time `json:"retry_at,omitempty"`
+	// Cause of the last failure of a dead job
+	Error    string    `json:"error,omitempty"`
+	FailedAt time.Time `json:"failed_at,omitempty"`
+}
+
+// QueueView is the queue as shown to admins, with up to a limited number
+// of jobs in each state
+type QueueView struct {
+	QueueStats
+	// Next to be picked up first
+	Pending    []QueuedJob `json:"pending_jobs"`
+	Processing []QueuedJob `json:"processing_jobs"`
+	// Next to be retried first
+	Delayed []QueuedJob `json:"delayed_jobs"`
+	// Latest first
+	Dead []QueuedJob `json:"dead_jobs"`
+}
+
+func (data jobData) queued() QueuedJob {
+	return QueuedJob{
+		ID:       data.ID,
+		User:     data.User,
+		Website:  data.Website,
+		Handler:  data.Handler,
+		Attempts: data.Attempts,
+	}
+}
+
+// Returns the jobs with the given ids, skipping the ones done meanwhile
+func getJobs(client *goredis.Client, ids []string) ([]QueuedJob, error) {
+	jobs := []QueuedJob{}
+	if len(ids) == 0 {
+		return jobs, nil
 	}
+	raws, err := client.HMGet(jobsKey, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range raws {
+		s, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		var data jobData
+		if err = json.Unmarshal([]byte(s), &data); err != nil {
+			continue
+		}
+		jobs = append(jobs, data.queued())
+	}
+	return jobs, nil
+}
 
+// Inspect returns the queue with up to limit jobs in each state
+func Inspect(limit int64) (QueueView, error) {
+	stats, err := Stats()
+	if err != nil {
+		return QueueView{}, err
+	}
+	view := QueueView{QueueStats: stats}
+	client := redis.GetRedisClient()
+	// Jobs are pushed to the head and picked up from the tail
+	pending, err := client.LRange(pendingKey, -limit, -1).Result()
+	if err != nil {
+		return QueueView{}, err
+	}
+	for i, j := 0, len(pending)-1; i < j; i, j = i+1, j-1 {
+		pending[i], pending[j] = pending[j], pending[i]
+	}
+	if view.Pending, err = getJobs(client, pending); err != nil {
+		return QueueView{}, err
+	}
+	processing, err := client.ZRange(processingKey, 0, limit-1).Result()
+	if err != nil {
+		return QueueView{}, err
+	}
+	if view.Processing, err = getJobs(client, processing); err != nil {
+		return QueueView{}, err
+	}
+	delayed, err := client.ZRangeWithScores(delayedKey, 0, limit-1).Result()
+	if err != nil {
+		return QueueView{}, err
+	}
+	retryAt := map[string]time.Time{}
+	ids := make([]string, 0, len(delayed))
+	for _, z := range delayed {
+		id, _ := z.Member.(string)
+		ids = append(ids, id)
+		retryAt[id] = time.Unix(int64(z.Score), 0).UTC()
+	}
+	if view.Delayed, err = getJobs(client, ids); err != nil {
+		return QueueView{}, err
+	}
+	for i := range view.Delayed {
+		view.Delayed[i].RetryAt = retryAt[view.Delayed[i].ID]
+	}
+	dead, err := client.LRange(deadKey, 0, limit-1).Result()
+	if err != nil {
+		return QueueView{}, err
+	}
+	view.Dead = []QueuedJob{}
+	for _, raw := range dead {
+		var job deadJob
+		if err = json.Unmarshal([]byte(raw), &job); err != nil {
+			continue
+		}
+		queued := job.queued()
+		queued.Error = job.Error
+		queued.FailedAt = job.FailedAt
+		view.Dead = append(view.Dead, queued)
+	}
+	return view, nil
 }
@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	goredis "github.com/go-redis/redis"
+	"github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/services/redis"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func noop(user bson.ObjectId, website string, ctx context.Context) error {
+	return nil
+}
+
+// Id of the queued job having the key
+func queuedID(t *testing.T, key string) string {
+	ids, err := redis.GetRedisClient().HGetAll(jobKeysKey).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, k := range ids {
+		if k == key {
+			return id
+		}
+	}
+	t.Fatal("job not queued")
+	return ""
+}
+
+// Takes the pending job off the queue as a worker would
+func claim(id string) {
+	client := redis.GetRedisClient()
+	client.LRem(pendingKey, 0, id)
+	client.ZAdd(processingKey, goredis.Z{Score: float64(time.Now().Add(visibilityTimeout).Unix()), Member: id})
+}
+
+func TestDroppedJobs(t *testing.T) {
+	client := redis.GetRedisClient()
+
+	Convey("Subject: Jobs whose data is lost\n", t, func() {
+		job := NewJob(bson.NewObjectId(), "testsite", noop)
+		So(Enqueue(job), ShouldBeNil)
+		So(Enqueue(job), ShouldEqual, errors.ErrJobAlreadyQueued)
+		id := queuedID(t, job.key())
+		claim(id)
+
+		Convey("A job with missing data should free its key", func() {
+			client.HDel(jobsKey, id)
+			perform(client, id)
+			queued, err := IsQueued(bson.ObjectId(job.user), "testsite")
+			So(err, ShouldBeNil)
+			So(queued, ShouldBeFalse)
+			So(Enqueue(job), ShouldBeNil)
+			id = queuedID(t, job.key())
+			claim(id)
+			So(remove(client, id), ShouldBeNil)
+		})
+		Convey("A job with corrupt data should free its key", func() {
+			client.HSet(jobsKey, id, "{")
+			perform(client, id)
+			So(client.HExists(jobsKey, id).Val(), ShouldBeFalse)
+			So(Enqueue(job), ShouldBeNil)
+			id = queuedID(t, job.key())
+			claim(id)
+			So(remove(client, id), ShouldBeNil)
+		})
+	})
+}
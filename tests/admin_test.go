@@ -0,0 +1,117 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/globalsign/mgo/bson"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/services/auth"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func addAdminTestUser(t *testing.T, username string) bson.ObjectId {
+	id, err := models.AddUser(types.User{
+		Email:    username + "@abc.com",
+		Username: username,
+		FullName: "Admin Test User",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bson.ObjectIdHex(id)
+}
+
+// Actions recorded in the audit log for the target user, latest first
+func auditedActions(t *testing.T, target bson.ObjectId) []types.AuditEntry {
+	page, err := models.GetAuditLog(target, types.Cursor{Time: time.Now().Add(time.Minute)}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page.Data
+}
+
+// Refreshes the session, returning the new access token
+func refreshAccessToken(refreshToken string) (int, string) {
+	r, _ := http.NewRequest("POST", "/v1/user/refresh", strings.NewReader(url.Values{
+		"refresh_token": {refreshToken},
+	}.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	beego.BeeApp.Handlers.ServeHTTP(w, r)
+	var refreshed auth.Tokens
+	json.Unmarshal(w.Body.Bytes(), &refreshed)
+	return w.Code, refreshed.AccessToken
+}
+
+func TestAuditedActions(t *testing.T) {
+	admin := bson.NewObjectId()
+	target := addAdminTestUser(t, "audittarget")
+
+	Convey("Subject: Audited admin actions\n", t, func() {
+		Convey("Blacklisting should be recorded and take effect", func() {
+			So(models.BlacklistUser(admin, target, map[string]string{"reason": "spam"}), ShouldBeNil)
+			So(auth.IsUserBlacklisted(target), ShouldBeTrue)
+			entries := auditedActions(t, target)
+			So(entries, ShouldNotBeEmpty)
+			So(entries[0].Action, ShouldEqual, types.AuditBlacklist)
+			So(entries[0].Admin, ShouldEqual, admin)
+			So(entries[0].Details["reason"], ShouldEqual, "spam")
+			So(models.WhitelistUser(admin, target, nil), ShouldBeNil)
+			So(auth.IsUserBlacklisted(target), ShouldBeFalse)
+		})
+		Convey("The action should be recorded before it is taken", func() {
+			// Whitelisting a user who isn't blacklisted fails, after the entry is made
+			So(models.WhitelistUser(admin, target, nil), ShouldNotBeNil)
+			entries := auditedActions(t, target)
+			So(entries, ShouldNotBeEmpty)
+			So(entries[0].Action, ShouldEqual, types.AuditWhitelist)
+		})
+		Convey("Actions of the command line tools should be recorded without an admin", func() {
+			deleted := addAdminTestUser(t, "auditdeleted")
+			So(models.DeleteUser("", deleted, map[string]string{"via": "cli"}), ShouldBeNil)
+			_, err := models.LookupUser(deleted.Hex())
+			So(err, ShouldNotBeNil)
+			So(auth.IsUserBlacklisted(deleted), ShouldBeTrue)
+			entries := auditedActions(t, deleted)
+			So(entries, ShouldHaveLength, 1)
+			So(entries[0].Action, ShouldEqual, types.AuditDeleteUser)
+			So(entries[0].Admin, ShouldEqual, bson.ObjectId(""))
+			So(entries[0].Details["username"], ShouldEqual, "auditdeleted")
+			So(entries[0].Details["via"], ShouldEqual, "cli")
+		})
+	})
+}
+
+func TestAdminUIDs(t *testing.T) {
+	listed := addAdminTestUser(t, "listedadmin")
+	other := addAdminTestUser(t, "unlistedadmin")
+	adminUIDs := beego.AppConfig.String("ADMIN_UIDS")
+	beego.AppConfig.Set("ADMIN_UIDS", listed.Hex())
+	defer beego.AppConfig.Set("ADMIN_UIDS", adminUIDs)
+
+	Convey("Subject: Admins listed in ADMIN_UIDS\n", t, func() {
+		Convey("Listed users should get the admin scope on refresh", func() {
+			tokens, err := auth.CreateSession(listed.Hex(), "", "", types.Scopes)
+			So(err, ShouldBeNil)
+			code, token := refreshAccessToken(tokens.RefreshToken)
+			So(code, ShouldEqual, http.StatusOK)
+			So(serveWithToken("GET", "/v1/admin/scheduler", token), ShouldEqual, http.StatusOK)
+		})
+		Convey("Other users should not be admins", func() {
+			tokens, err := auth.CreateSession(other.Hex(), "", "", types.Scopes)
+			So(err, ShouldBeNil)
+			code, token := refreshAccessToken(tokens.RefreshToken)
+			So(code, ShouldEqual, http.StatusOK)
+			So(serveWithToken("GET", "/v1/admin/scheduler", token), ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/astaxie/beego"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func serveWithKey(method string, path string, key string) int {
+	r, _ := http.NewRequest(method, path, nil)
+	r.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	beego.BeeApp.Handlers.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestAPIKeys(t *testing.T) {
+	id, err := models.AddUser(types.User{
+		Email:    "apikey@abc.com",
+		Username: "apikey",
+		FullName: "Key Holder",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := bson.ObjectIdHex(id)
+
+	Convey("Subject: API keys\n", t, func() {
+		created, err := models.CreateAPIKey(uid, "script", []string{"read:user", "write:user"})
+		So(err, ShouldBeNil)
+
+		Convey("The key should be returned once and only its hash stored", func() {
+			So(created.Key, ShouldStartWith, models.APIKeyPrefix)
+			So(created.Key, ShouldStartWith, created.Prefix)
+			keys, err := models.GetAPIKeys(uid)
+			So(err, ShouldBeNil)
+			So(keys, ShouldNotBeEmpty)
+			for _, key := range keys {
+				So(key.Hash, ShouldNotEqual, "")
+				So(key.Hash, ShouldNotContainSubstring, created.Key)
+			}
+		})
+		Convey("The key should be looked up by its hash", func() {
+			key, err := models.AuthenticateAPIKey(created.Key)
+			So(err, ShouldBeNil)
+			So(key.ID, ShouldEqual, created.ID)
+			So(key.UID, ShouldEqual, uid)
+			So(key.LastUsed.IsZero(), ShouldBeFalse)
+			_, err = models.AuthenticateAPIKey(created.Key + "0")
+			So(err, ShouldEqual, APIKeyNotFoundError)
+		})
+		Convey("The scopes of the key should be enforced", func() {
+			So(serveWithKey("GET", "/v1/user/", created.Key), ShouldEqual, http.StatusOK)
+			So(serveWithKey("GET", "/v1/feed/contests", created.Key), ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Account endpoints should not be reached with a key", func() {
+			So(serveWithKey("PUT", "/v1/user/", created.Key), ShouldEqual, http.StatusForbidden)
+			So(serveWithKey("POST", "/v1/user/logout", created.Key), ShouldEqual, http.StatusForbidden)
+			So(serveWithKey("PUT", "/v1/user/picture", created.Key), ShouldEqual, http.StatusForbidden)
+			So(serveWithKey("POST", "/v1/user/verify/codeforces/token", created.Key), ShouldEqual, http.StatusForbidden)
+			So(serveWithKey("POST", "/v1/user/oauth/github/link", created.Key), ShouldEqual, http.StatusForbidden)
+			So(serveWithKey("POST", "/v1/user/apikeys", created.Key), ShouldEqual, http.StatusForbidden)
+		})
+		Convey("A revoked key should not authenticate", func() {
+			So(models.DeleteAPIKey(uid, created.ID), ShouldBeNil)
+			_, err := models.AuthenticateAPIKey(created.Key)
+			So(err, ShouldEqual, APIKeyNotFoundError)
+			So(serveWithKey("GET", "/v1/user/", created.Key), ShouldEqual, http.StatusUnauthorized)
+			So(models.DeleteAPIKey(uid, created.ID), ShouldEqual, APIKeyNotFoundError)
+		})
+	})
+}
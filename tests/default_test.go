@@ -32,7 +32,8 @@ func TestGetAllUsers(t *testing.T) {
 		Institute: "IIT Roorkee",
 		Password:  "password",
 	})
-	token := auth.GenerateToken(uid)
+	tokens, _ := auth.CreateSession(uid, "", "", types.Scopes)
+	token := tokens.AccessToken
 	r, _ := http.NewRequest("GET", "/v1/user/all", nil)
 	r.Header.Set("Authorization", token)
 	w := httptest.NewRecorder()
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/scrappers"
+	"github.com/mdg-iitr/Codephile/services/worker"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Site whose handles are never found
+const failingSite = "failingsite"
+
+type failingScrapper struct{}
+
+func (failingScrapper) CheckHandle() (bool, error) {
+	return false, nil
+}
+
+func (failingScrapper) GetSubmissions(after time.Time) ([]types.Submission, error) {
+	return nil, NewScrapeError(failingSite, ScrapeHandleNotFound, errors.New("no such handle"))
+}
+
+func (failingScrapper) GetProfileInfo() (types.ProfileInfo, error) {
+	return types.ProfileInfo{}, NewScrapeError(failingSite, ScrapeHandleNotFound, errors.New("no such handle"))
+}
+
+func init() {
+	scrappers.Register(scrappers.Site{
+		Name:      failingSite,
+		URLPrefix: "https://failing.example",
+		New: func(handle string, ctx context.Context) scrappers.Scrapper {
+			return failingScrapper{}
+		},
+	})
+	worker.SetReporter(models.RecordFetchResult)
+	worker.Start()
+}
+
+func TestFetchStatusAfterLinkingHandle(t *testing.T) {
+	id, err := models.AddUser(types.User{
+		Email:    "fetch@abc.com",
+		Username: "fetch",
+		FullName: "Fetch User",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := bson.ObjectIdHex(id)
+	update := types.User{Handle: types.Handle{failingSite: "nobody"}}
+	_, err = models.UpdateUser(uid, &update, context.Background())
+
+	var status types.FetchStatus
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+		statuses, err := models.GetFetchStatus(uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status = statuses[failingSite]
+		if status.Result != types.FetchNotAttempted {
+			break
+		}
+	}
+	Convey("Subject: Fetch status after linking a handle\n", t, func() {
+		So(err, ShouldBeNil)
+		Convey("The failure of the fetch should be recorded", func() {
+			So(status.Result, ShouldEqual, types.FetchFailed)
+			So(status.ErrorKind, ShouldEqual, string(ScrapeHandleNotFound))
+		})
+	})
+}
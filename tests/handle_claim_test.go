@@ -0,0 +1,56 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLinkingVerifiedHandle(t *testing.T) {
+	owner, err := models.AddUser(types.User{
+		Email:    "owner@abc.com",
+		Username: "owner",
+		FullName: "Handle Owner",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := models.AddUser(types.User{
+		Email:    "other@abc.com",
+		Username: "other",
+		FullName: "Other User",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess := db.NewUserCollectionSession()
+	err = sess.Collection.UpdateId(bson.ObjectIdHex(owner), bson.M{"$set": bson.M{
+		"handle." + failingSite:           "claimed",
+		"verified_handles." + failingSite: "claimed",
+	}})
+	sess.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Subject: Linking a handle verified by another user\n", t, func() {
+		Convey("The handle should not be linked", func() {
+			update := types.User{Handle: types.Handle{failingSite: "claimed"}}
+			_, err := models.UpdateUser(bson.ObjectIdHex(other), &update, context.Background())
+			So(err, ShouldEqual, HandleClaimedError)
+		})
+		Convey("Other handles should still be linked", func() {
+			update := types.User{Handle: types.Handle{failingSite: "unclaimed"}}
+			_, err := models.UpdateUser(bson.ObjectIdHex(other), &update, context.Background())
+			So(err, ShouldBeNil)
+		})
+	})
+}
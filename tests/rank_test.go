@@ -0,0 +1,80 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/astaxie/beego"
+	"github.com/globalsign/mgo/bson"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/services/auth"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const rankInstitute = "Rank Institute"
+
+// Adds a user of the rank institute with the codeforces world rank, or
+// without a codeforces handle if rank is empty
+func addRankedUser(t *testing.T, username string, rank string) bson.ObjectId {
+	id, err := models.AddUser(types.User{
+		Email:     username + "@abc.com",
+		Username:  username,
+		FullName:  "Ranked User",
+		Institute: rankInstitute,
+		Password:  "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := bson.ObjectIdHex(id)
+	if rank != "" {
+		sess := db.NewUserCollectionSession()
+		err = sess.Collection.UpdateId(uid, bson.M{"$set": bson.M{
+			"handle.codeforces":               username,
+			"profiles.codeforcesProfile.rank": rank,
+		}})
+		sess.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return uid
+}
+
+func TestInstituteRank(t *testing.T) {
+	viewer := addRankedUser(t, "rankviewer", "")
+	good := addRankedUser(t, "rankgood", "10")
+	better := addRankedUser(t, "rankbetter", "5")
+	blocker := addRankedUser(t, "rankblocker", "1")
+	if err := models.BlockUser(blocker, viewer); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.CreateSession(viewer.Hex(), "", "", types.Scopes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Subject: Institute rank\n", t, func() {
+		r, _ := http.NewRequest("GET", "/v1/rank/codeforces?institute=Rank+Institute", nil)
+		r.Header.Set("Authorization", tokens.AccessToken)
+		w := httptest.NewRecorder()
+		beego.BeeApp.Handlers.ServeHTTP(w, r)
+		So(w.Code, ShouldEqual, http.StatusOK)
+		var ranked []types.SearchDoc
+		So(json.Unmarshal(w.Body.Bytes(), &ranked), ShouldBeNil)
+
+		Convey("Users should be ordered by their world rank", func() {
+			So(ranked, ShouldHaveLength, 2)
+			So(ranked[0].ID, ShouldEqual, better)
+			So(ranked[1].ID, ShouldEqual, good)
+		})
+		Convey("Users who blocked the viewer should not be compared with", func() {
+			So(serveWithToken("GET", "/v1/friends/compare?uid2="+blocker.Hex(), tokens.AccessToken), ShouldEqual, http.StatusForbidden)
+			So(serveWithToken("GET", "/v1/friends/compare?uid2="+good.Hex(), tokens.AccessToken), ShouldEqual, http.StatusOK)
+		})
+	})
+}
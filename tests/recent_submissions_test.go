@@ -0,0 +1,66 @@
+package test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/db"
+	"github.com/mdg-iitr/Codephile/models/types"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Adds a user with n submissions, made a minute apart
+func addUserWithSubmissions(t *testing.T, username string, n int) bson.ObjectId {
+	id, err := models.AddUser(types.User{
+		Email:    username + "@abc.com",
+		Username: username,
+		FullName: "Active User",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := bson.ObjectIdHex(id)
+	sess := db.NewSubmissionCollectionSession()
+	defer sess.Close()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		err = sess.Collection.Insert(types.Submission{
+			Name:         "Problem " + strconv.Itoa(i),
+			URL:          "https://codeforces.com/problemset/problem/" + strconv.Itoa(i) + "/A",
+			CreationDate: start.Add(time.Duration(i) * time.Minute),
+			UserID:       uid,
+			Site:         "codeforces",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return uid
+}
+
+func TestRecentSubmissionsOfAllUsers(t *testing.T) {
+	active := addUserWithSubmissions(t, "recentactive", 7)
+	quiet := addUserWithSubmissions(t, "recentquiet", 2)
+	users, err := models.GetAllUsers()
+	recent := make(map[bson.ObjectId][]types.Submission)
+	for _, user := range users {
+		recent[user.ID] = user.Submissions
+	}
+
+	Convey("Subject: Recent submissions of all the users\n", t, func() {
+		So(err, ShouldBeNil)
+		Convey("Only the latest 5 submissions should be listed, latest first", func() {
+			So(recent[active], ShouldHaveLength, 5)
+			So(recent[active][0].Name, ShouldEqual, "Problem 6")
+			So(recent[active][4].Name, ShouldEqual, "Problem 2")
+		})
+		Convey("Users with fewer submissions should list all of them", func() {
+			So(recent[quiet], ShouldHaveLength, 2)
+			So(recent[quiet][0].Name, ShouldEqual, "Problem 1")
+		})
+	})
+}
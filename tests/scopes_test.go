@@ -0,0 +1,125 @@
+package test
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/globalsign/mgo/bson"
+	"github.com/mdg-iitr/Codephile/controllers"
+	"github.com/mdg-iitr/Codephile/models"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/services/auth"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func serveWithToken(method string, path string, token string) int {
+	r, _ := http.NewRequest(method, path, nil)
+	r.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	beego.BeeApp.Handlers.ServeHTTP(w, r)
+	return w.Code
+}
+
+// Scope named by the @Security annotation of the doc comment, empty if
+// there is none
+func annotatedScope(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	for _, comment := range doc.List {
+		fields := strings.Fields(strings.TrimPrefix(comment.Text, "//"))
+		if len(fields) == 3 && fields[0] == "@Security" && fields[1] == "token_auth" {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+func TestRouteScopesMatchAnnotations(t *testing.T) {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), "../controllers", nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Convey("Subject: Scopes of the endpoints\n", t, func() {
+		Convey("Scope checked for every endpoint should be the documented one", func() {
+			for _, pkg := range pkgs {
+				for _, file := range pkg.Files {
+					for _, decl := range file.Decls {
+						fn, ok := decl.(*ast.FuncDecl)
+						// Only the exported methods are routed
+						if !ok || fn.Recv == nil || !fn.Name.IsExported() {
+							continue
+						}
+						recv, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+						if !ok {
+							continue
+						}
+						controller := recv.X.(*ast.Ident).Name
+						So(controller+"."+fn.Name.Name+" "+controllers.RouteScope(controller, fn.Name.Name),
+							ShouldEqual, controller+"."+fn.Name.Name+" "+annotatedScope(fn.Doc))
+					}
+				}
+			}
+		})
+	})
+}
+
+func TestCheckScope(t *testing.T) {
+	id, err := models.AddUser(types.User{
+		Email:    "scopes@abc.com",
+		Username: "scopes",
+		FullName: "Scoped User",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := bson.ObjectIdHex(id)
+
+	Convey("Subject: Scopes of the tokens\n", t, func() {
+		Convey("Endpoints should be reached only with their scope", func() {
+			tokens, err := auth.CreateSession(id, "", "", []string{"read:user"})
+			So(err, ShouldBeNil)
+			So(serveWithToken("GET", "/v1/user/", tokens.AccessToken), ShouldEqual, http.StatusOK)
+			So(serveWithToken("GET", "/v1/feed/contests", tokens.AccessToken), ShouldEqual, http.StatusForbidden)
+			So(serveWithToken("GET", "/v1/admin/scheduler", tokens.AccessToken), ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Tokens issued before the scopes should only read", func() {
+			legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+				ExpiresAt: time.Now().Add(time.Hour).Unix(),
+				Subject:   id,
+			})
+			token, err := legacy.SignedString([]byte(os.Getenv("HMACKEY")))
+			So(err, ShouldBeNil)
+			So(serveWithToken("GET", "/v1/user/", token), ShouldEqual, http.StatusOK)
+			So(serveWithToken("PUT", "/v1/user/", token), ShouldEqual, http.StatusForbidden)
+			So(serveWithToken("GET", "/v1/admin/scheduler", token), ShouldEqual, http.StatusForbidden)
+		})
+		Convey("Refreshed tokens should get the scopes the user holds now", func() {
+			tokens, err := auth.CreateSession(id, "", "", types.Scopes)
+			So(err, ShouldBeNil)
+			So(models.SetRole(uid, types.RoleAdmin), ShouldBeNil)
+			r, _ := http.NewRequest("POST", "/v1/user/refresh", strings.NewReader(url.Values{
+				"refresh_token": {tokens.RefreshToken},
+			}.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			beego.BeeApp.Handlers.ServeHTTP(w, r)
+			So(w.Code, ShouldEqual, http.StatusOK)
+			var refreshed auth.Tokens
+			So(json.Unmarshal(w.Body.Bytes(), &refreshed), ShouldBeNil)
+			So(serveWithToken("GET", "/v1/admin/scheduler", refreshed.AccessToken), ShouldEqual, http.StatusOK)
+		})
+	})
+}
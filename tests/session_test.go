@@ -0,0 +1,95 @@
+package test
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/globalsign/mgo/bson"
+	. "github.com/mdg-iitr/Codephile/errors"
+	"github.com/mdg-iitr/Codephile/models/types"
+	"github.com/mdg-iitr/Codephile/services/auth"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func parseToken(t *testing.T, token string) *jwt.Token {
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("HMACKEY")), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func allScopes(string) []string {
+	return types.Scopes
+}
+
+func TestSessions(t *testing.T) {
+	uid := bson.NewObjectId().Hex()
+
+	Convey("Subject: Sessions\n", t, func() {
+		first, err := auth.CreateSession(uid, "test", "127.0.0.1", types.Scopes)
+		So(err, ShouldBeNil)
+
+		Convey("Refreshing should rotate the refresh token", func() {
+			refreshed, err := auth.RefreshSession(first.RefreshToken, allScopes)
+			So(err, ShouldBeNil)
+			So(refreshed.RefreshToken, ShouldNotEqual, first.RefreshToken)
+			So(auth.SessionID(parseToken(t, refreshed.AccessToken)), ShouldEqual, auth.SessionID(parseToken(t, first.AccessToken)))
+			_, err = auth.RefreshSession(refreshed.RefreshToken, allScopes)
+			So(err, ShouldBeNil)
+		})
+		Convey("Reusing a refresh token should revoke the session", func() {
+			refreshed, err := auth.RefreshSession(first.RefreshToken, allScopes)
+			So(err, ShouldBeNil)
+			_, err = auth.RefreshSession(first.RefreshToken, allScopes)
+			So(err, ShouldEqual, InvalidRefreshTokenError)
+			_, err = auth.RefreshSession(refreshed.RefreshToken, allScopes)
+			So(err, ShouldEqual, InvalidRefreshTokenError)
+			So(auth.IsTokenBlacklisted(parseToken(t, refreshed.AccessToken)), ShouldBeTrue)
+		})
+		Convey("Revoking a session should leave the other sessions", func() {
+			second, err := auth.CreateSession(uid, "test", "127.0.0.1", types.Scopes)
+			So(err, ShouldBeNil)
+			firstToken := parseToken(t, first.AccessToken)
+			So(auth.RevokeSession(uid, auth.SessionID(firstToken)), ShouldBeNil)
+			So(auth.IsTokenBlacklisted(firstToken), ShouldBeTrue)
+			_, err = auth.RefreshSession(first.RefreshToken, allScopes)
+			So(err, ShouldEqual, InvalidRefreshTokenError)
+			So(auth.IsTokenBlacklisted(parseToken(t, second.AccessToken)), ShouldBeFalse)
+			_, err = auth.RefreshSession(second.RefreshToken, allScopes)
+			So(err, ShouldBeNil)
+		})
+		Convey("Only one of the concurrent refreshes with a token should succeed", func() {
+			const refreshes = 10
+			var wg sync.WaitGroup
+			errs := make(chan error, refreshes)
+			for i := 0; i < refreshes; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := auth.RefreshSession(first.RefreshToken, allScopes)
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			succeeded := 0
+			for err := range errs {
+				if err == nil {
+					succeeded++
+				} else {
+					So(err, ShouldEqual, InvalidRefreshTokenError)
+				}
+			}
+			So(succeeded, ShouldEqual, 1)
+		})
+		Convey("Sessions of a user should not be revoked by another", func() {
+			other := bson.NewObjectId().Hex()
+			So(auth.RevokeSession(other, auth.SessionID(parseToken(t, first.AccessToken))), ShouldEqual, SessionNotFoundError)
+		})
+	})
+}